@@ -0,0 +1,215 @@
+package factorlog
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what an asynchronous logger does with a
+// record when its queue is full. See FactorLog.SetAsync().
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes the caller wait until there is room in the queue.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the record being logged.
+	OverflowDropNewest
+	// OverflowDropLowest discards the queued record with the lowest
+	// severity, or the record being logged if its severity is lower still.
+	OverflowDropLowest
+)
+
+// asyncRecord is a record waiting to be formatted and written.
+type asyncRecord struct {
+	context LogContext
+	stack   []byte
+	sinks   []*Sink
+	errs    *FactorLog       // handles write errors
+	after   []writeHookEntry // called once the record is written
+}
+
+// write writes the record to its sinks, then calls its write hooks.
+func (r *asyncRecord) write() error {
+	err := writeSinks(r.errs, r.sinks, r.context, r.stack)
+	runWriteHooks(r.after, r.context, err)
+	return err
+}
+
+// asyncQueue is a bounded queue of records that a background
+// goroutine formats and writes to their sinks.
+type asyncQueue struct {
+	mu      sync.Mutex
+	cond    sync.Cond // broadcast whenever the queue changes
+	records []asyncRecord
+	head    int // index of the oldest record
+	n       int // number of queued records
+	policy  OverflowPolicy
+	busy    bool // the worker is writing a record
+	closed  bool
+	dropped uint64
+	done    chan struct{}
+}
+
+func newAsyncQueue(size int, policy OverflowPolicy) *asyncQueue {
+	q := &asyncQueue{
+		records: make([]asyncRecord, size),
+		policy:  policy,
+		done:    make(chan struct{}),
+	}
+	q.cond.L = &q.mu
+	go q.run()
+	return q
+}
+
+// push adds a record to the queue, applying the overflow
+// policy if it is full.
+func (q *asyncQueue) push(r asyncRecord) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.n == len(q.records) && !q.closed {
+		switch q.policy {
+		case OverflowDropNewest:
+			atomic.AddUint64(&q.dropped, 1)
+			return
+		case OverflowDropLowest:
+			// Find the oldest of the lowest severity records.
+			lowest := -1
+			for i := 0; i < q.n; i++ {
+				j := (q.head + i) % len(q.records)
+				if lowest == -1 || q.records[j].context.Severity < q.records[lowest].context.Severity {
+					lowest = j
+				}
+			}
+
+			atomic.AddUint64(&q.dropped, 1)
+			if r.context.Severity <= q.records[lowest].context.Severity {
+				return
+			}
+
+			// Close the gap by shifting the newer records back.
+			for j := lowest; ; {
+				next := (j + 1) % len(q.records)
+				if next == (q.head+q.n)%len(q.records) {
+					q.records[j] = asyncRecord{}
+					break
+				}
+				q.records[j] = q.records[next]
+				j = next
+			}
+			q.n--
+		default:
+			q.cond.Wait()
+		}
+	}
+
+	if q.closed {
+		// The worker is gone, so write the record ourselves.
+		r.write()
+		return
+	}
+
+	q.records[(q.head+q.n)%len(q.records)] = r
+	q.n++
+	q.cond.Broadcast()
+}
+
+// run writes records until the queue is closed and empty.
+func (q *asyncQueue) run() {
+	q.mu.Lock()
+	for {
+		for q.n == 0 && !q.closed {
+			q.cond.Wait()
+		}
+
+		if q.n == 0 {
+			break
+		}
+
+		r := q.records[q.head]
+		q.records[q.head] = asyncRecord{}
+		q.head = (q.head + 1) % len(q.records)
+		q.n--
+		q.busy = true
+		q.cond.Broadcast()
+		q.mu.Unlock()
+
+		r.write()
+
+		q.mu.Lock()
+		q.busy = false
+		q.cond.Broadcast()
+	}
+	q.mu.Unlock()
+	close(q.done)
+}
+
+// flush waits until every queued record has been written.
+func (q *asyncQueue) flush() {
+	q.mu.Lock()
+	for q.n > 0 || q.busy {
+		q.cond.Wait()
+	}
+	q.mu.Unlock()
+}
+
+// close writes the remaining records and stops the worker.
+func (q *asyncQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+	<-q.done
+}
+
+// SetAsync makes this logger hand records to a background goroutine,
+// which formats and writes them, instead of writing them in the
+// calling goroutine. size is the number of records the queue holds
+// and policy decides what happens when it is full. A size of 0 or
+// less turns asynchronous mode off again after writing any queued
+// records.
+// Records are formatted after the logging call returns, so arguments
+// must not be modified after they are passed to the logger.
+// Call Flush() or Close() before the program exits, or queued records
+// will be lost. Fatal() flushes the logger before exiting.
+// Example:
+//   log.SetAsync(1024, factorlog.OverflowDropLowest)
+//   defer log.Close()
+func (l *FactorLog) SetAsync(size int, policy OverflowPolicy) {
+	l = l.cfg()
+	l.mu.Lock()
+	var old *asyncQueue
+	if l.owns(ownAsync) {
+		old = l.loadAsync()
+	}
+	var q *asyncQueue
+	if size > 0 {
+		q = newAsyncQueue(size, policy)
+	}
+	l.async.Store(q)
+	l.setOwn(ownAsync)
+	l.mu.Unlock()
+
+	// Records logged while the queue is being swapped may still
+	// be pushed to the old queue. It writes them synchronously
+	// once it is closed.
+	if old != nil {
+		old.close()
+	}
+}
+
+// Dropped returns the number of records an asynchronous logger has
+// discarded because its queue was full since SetAsync() was last called.
+func (l *FactorLog) Dropped() uint64 {
+	q := l.cfg().loadAsync()
+	if q == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&q.dropped)
+}
+
+// SetAsync sets asynchronous mode for the standard logger.
+// See FactorLog.SetAsync().
+func SetAsync(size int, policy OverflowPolicy) {
+	std.SetAsync(size, policy)
+}
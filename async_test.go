@@ -0,0 +1,151 @@
+package factorlog
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gateWriter blocks every write until release is closed, and
+// signals entered when a write starts.
+type gateWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	entered chan struct{}
+	release chan struct{}
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{
+		entered: make(chan struct{}, 100),
+		release: make(chan struct{}),
+	}
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	w.entered <- struct{}{}
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gateWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsync(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, NewStdFormatter("%{Message}"))
+	l.SetAsync(10, OverflowBlock)
+	for i := 0; i < 5; i++ {
+		l.Info(i)
+	}
+	l.Flush()
+
+	if buf.String() != "0\n1\n2\n3\n4\n" {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", "0\n1\n2\n3\n4\n", buf.String())
+	}
+
+	// After Close, records are written synchronously again.
+	l.Close()
+	buf.Reset()
+	l.Info("hey")
+	if buf.String() != "hey\n" {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", "hey\n", buf.String())
+	}
+}
+
+func TestAsyncDropNewest(t *testing.T) {
+	w := newGateWriter()
+	l := New(w, NewStdFormatter("%{Message}"))
+	l.SetAsync(2, OverflowDropNewest)
+
+	l.Info("a")
+	<-w.entered // the worker is now busy writing "a"
+	l.Info("b")
+	l.Info("c")
+	l.Info("d")
+
+	close(w.release)
+	l.Flush()
+
+	if w.String() != "a\nb\nc\n" {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", "a\nb\nc\n", w.String())
+	}
+	if l.Dropped() != 1 {
+		t.Fatalf("expected 1 dropped record, got %d", l.Dropped())
+	}
+	l.Close()
+}
+
+func TestAsyncDropLowest(t *testing.T) {
+	w := newGateWriter()
+	l := New(w, NewStdFormatter("%{Message}"))
+	l.SetAsync(2, OverflowDropLowest)
+
+	l.Info("a")
+	<-w.entered
+	l.Debug("b")
+	l.Error("c")
+	l.Warn("d")  // drops "b"
+	l.Trace("e") // lower than anything queued, so dropped itself
+
+	close(w.release)
+	l.Flush()
+
+	if w.String() != "a\nc\nd\n" {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", "a\nc\nd\n", w.String())
+	}
+	if l.Dropped() != 2 {
+		t.Fatalf("expected 2 dropped records, got %d", l.Dropped())
+	}
+	l.Close()
+}
+
+func TestAsyncBlock(t *testing.T) {
+	w := newGateWriter()
+	l := New(w, NewStdFormatter("%{Message}"))
+	l.SetAsync(1, OverflowBlock)
+
+	l.Info("a")
+	<-w.entered
+	l.Info("b")
+
+	done := make(chan struct{})
+	go func() {
+		l.Info("c")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("expected the caller to block while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(w.release)
+	<-done
+	l.Close()
+
+	if w.String() != "a\nb\nc\n" {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", "a\nb\nc\n", w.String())
+	}
+	if l.Dropped() != 0 {
+		t.Fatalf("expected no dropped records, got %d", l.Dropped())
+	}
+}
+
+func BenchmarkFactorLogAsync(b *testing.B) {
+	buf := &bytes.Buffer{}
+	l := New(buf, NewStdFormatter("%{Date} %{Time} %{File}:%{Line}: %{Message}"))
+	l.SetAsync(1024, OverflowBlock)
+	b.ResetTimer()
+	for x := 0; x < b.N; x++ {
+		l.Info("hey")
+	}
+	l.Close()
+}
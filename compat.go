@@ -0,0 +1,172 @@
+package factorlog
+
+import (
+	"io"
+)
+
+// These flags are the ones of Go's log package, with the same values,
+// so code written for it keeps working when it imports factorlog
+// instead. SetFlags() turns them into a StdFormatter.
+const (
+	Ldate         = 1 << iota     // the date in the local time zone: 2009/01/23
+	Ltime                         // the time in the local time zone: 01:23:23
+	Lmicroseconds                 // microsecond resolution: 01:23:23.123123. assumes Ltime.
+	Llongfile                     // full file name and line number: /a/b/c/d.go:23
+	Lshortfile                    // final file name element and line number: d.go:23. overrides Llongfile
+	LUTC                          // if Ldate or Ltime is set, use UTC rather than the local time zone
+	Lmsgprefix                    // move the prefix from the beginning of the line to before the message
+	LstdFlags     = Ldate | Ltime // initial values for the standard logger
+)
+
+// SetFlags sets the formatter of this logger to a StdFormatter that
+// writes records like Go's log package does with the given flags and
+// the prefix set by SetPrefix. It replaces the formatter of the first
+// sink, like SetFormatter.
+// Example:
+//   log.SetFlags(log.LstdFlags | log.Lshortfile)
+//   // 2009/01/23 01:23:23 d.go:23: message
+func (l *FactorLog) SetFlags(flags int) {
+	l = l.cfg()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inheritFlags()
+	l.flags = flags
+	l.claimSinks()
+	l.replaceFirstSink(nil, newFlagsFormatter(l.flags, l.prefix))
+}
+
+// Flags returns the flags last set with SetFlags. The standard logger
+// starts with LstdFlags, though its formatter writes dates as
+// 2009-01-23 until SetFlags or SetPrefix is called.
+func (l *FactorLog) Flags() int {
+	l = l.cfg().owner(ownSinks)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.flags
+}
+
+// SetPrefix sets the prefix written at the beginning of each record,
+// or before the message with Lmsgprefix. Like SetFlags, it replaces
+// the formatter of the first sink.
+func (l *FactorLog) SetPrefix(prefix string) {
+	l = l.cfg()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inheritFlags()
+	l.prefix = prefix
+	l.claimSinks()
+	l.replaceFirstSink(nil, newFlagsFormatter(l.flags, l.prefix))
+}
+
+// inheritFlags copies the flags and prefix of the parent of a named
+// logger that inherits its sinks, before it sets its own. Must be
+// called with l.mu held.
+func (l *FactorLog) inheritFlags() {
+	if !l.owns(ownSinks) {
+		l.flags = l.parent.Flags()
+		l.prefix = l.parent.Prefix()
+	}
+}
+
+// Prefix returns the prefix set with SetPrefix.
+func (l *FactorLog) Prefix() string {
+	l = l.cfg().owner(ownSinks)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.prefix
+}
+
+// Writer returns the writer of the first sink of this logger.
+func (l *FactorLog) Writer() io.Writer {
+	sinks := l.cfg().loadSinks()
+	if len(sinks) == 0 {
+		return nil
+	}
+	return sinks[0].Writer()
+}
+
+// SetPrintSeverity sets the severity of records written by Print,
+// Printf and Println, DEBUG by default. Code written for Go's log
+// package mostly calls Print, so this decides whether its records
+// pass the logger's severities.
+// Example:
+//   log.SetPrintSeverity(factorlog.INFO)
+func (l *FactorLog) SetPrintSeverity(sev Severity) {
+	l = l.cfg()
+	l.printSev.set(sev)
+	l.setOwn(ownPrint)
+}
+
+// PrintSeverity returns the severity of records written by Print.
+func (l *FactorLog) PrintSeverity() Severity {
+	if sev := l.cfg().owner(ownPrint).printSev.get(); sev != 0 {
+		return sev
+	}
+	return DEBUG
+}
+
+// newFlagsFormatter returns a StdFormatter that writes records like
+// a log.Logger with the given flags and prefix. The prefix is added
+// as a plain string, so it may contain "%{".
+func newFlagsFormatter(flags int, prefix string) *StdFormatter {
+	frmt := ""
+	if flags&Ldate != 0 {
+		frmt += `%{Time "2006/01/02"} `
+	}
+	if flags&Lmicroseconds != 0 {
+		frmt += `%{Time "15:04:05.000000"} `
+	} else if flags&Ltime != 0 {
+		frmt += `%{Time} `
+	}
+	if flags&Lshortfile != 0 {
+		frmt += `%{File}:%{Line}: `
+	} else if flags&Llongfile != 0 {
+		frmt += `%{FullFile}:%{Line}: `
+	}
+
+	f := &StdFormatter{UTC: flags&LUTC != 0}
+	if flags&Lmsgprefix == 0 {
+		f.appendString(prefix)
+	}
+	f.appendFormatter(NewStdFormatter(frmt))
+	if flags&Lmsgprefix != 0 {
+		f.appendString(prefix)
+	}
+	f.appendFormatter(NewStdFormatter("%{Message}"))
+	return f
+}
+
+// SetFlags sets the flags of the standard logger. See FactorLog.SetFlags().
+func SetFlags(flags int) {
+	std.SetFlags(flags)
+}
+
+// Flags returns the flags of the standard logger.
+func Flags() int {
+	return std.Flags()
+}
+
+// SetPrefix sets the prefix of the standard logger.
+func SetPrefix(prefix string) {
+	std.SetPrefix(prefix)
+}
+
+// Prefix returns the prefix of the standard logger.
+func Prefix() string {
+	return std.Prefix()
+}
+
+// Writer returns the writer of the standard logger.
+func Writer() io.Writer {
+	return std.Writer()
+}
+
+// SetPrintSeverity sets the severity of Print for the standard logger.
+func SetPrintSeverity(sev Severity) {
+	std.SetPrintSeverity(sev)
+}
+
+// PrintSeverity returns the severity of Print for the standard logger.
+func PrintSeverity() Severity {
+	return std.PrintSeverity()
+}
@@ -0,0 +1,64 @@
+package factorlog
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+func TestSetFlags(t *testing.T) {
+	var flagsTests = []struct {
+		flags  int
+		prefix string
+		re     string
+	}{
+		{0, "", `^hey\n$`},
+		{LstdFlags, "", `^\d{4}/\d\d/\d\d \d\d:\d\d:\d\d hey\n$`},
+		{Ltime | Lmicroseconds | LUTC, "", `^\d\d:\d\d:\d\d\.\d{6} hey\n$`},
+		{Lshortfile, "app: ", `^app: compat_test\.go:\d+: hey\n$`},
+		{Llongfile | Lmsgprefix, "%{Message} ", `^/.*/compat_test\.go:\d+: %\{Message\} hey\n$`},
+	}
+
+	for _, tt := range flagsTests {
+		buf := &bytes.Buffer{}
+		log := New(buf, NewStdFormatter("%{Message}"))
+		log.SetPrefix(tt.prefix)
+		log.SetFlags(tt.flags)
+		log.Print("hey")
+		if !regexp.MustCompile(tt.re).MatchString(buf.String()) {
+			t.Errorf("flags %d: expected %#v to match %s", tt.flags, buf.String(), tt.re)
+		}
+		if log.Flags() != tt.flags || log.Prefix() != tt.prefix || log.Writer() != buf {
+			t.Errorf("flags %d: expected the getters to return what was set", tt.flags)
+		}
+	}
+}
+
+func TestSetFlagsNamed(t *testing.T) {
+	t.Cleanup(func() { forgetNamed("testsetflags") })
+	buf := &bytes.Buffer{}
+	parent := Named("testsetflags")
+	parent.SetOutput(buf)
+	parent.SetPrefix("parent: ")
+	child := Named("testsetflags.child")
+	child.SetFlags(Lmsgprefix)
+
+	child.Print("hey")
+	parent.Print("hey")
+	if buf.String() != "parent: hey\nparent: hey\n" || child.Prefix() != "parent: " {
+		t.Fatalf("expected the child to keep the parent's prefix, got %#v", buf.String())
+	}
+}
+
+func TestPrintSeverity(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(buf, NewStdFormatter("%{SEVERITY} %{Message}"))
+	log.SetSeverities(INFO)
+	log.Print("hidden")
+	log.SetPrintSeverity(INFO)
+	log.With("a", 1).Printf("hey %d", 1)
+	log.V(0).Println("there")
+	if buf.String() != "INFO hey 1\nINFO there\n" {
+		t.Fatalf("expected Print to use the print severity, got %#v", buf.String())
+	}
+}
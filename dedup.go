@@ -0,0 +1,116 @@
+package factorlog
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// deduper holds back consecutive identical records. See SetDedup().
+type deduper struct {
+	l       *FactorLog
+	timeout time.Duration
+
+	mu       sync.Mutex
+	last     LogContext // the last record written
+	message  string     // its rendered message
+	repeated int        // repeats of last held back
+	timer    *time.Timer
+}
+
+// SetDedup turns on duplicate suppression, like syslogd does: a record
+// with the same severity, call site, message and fields as the
+// previous one isn't written. Instead, once a different record is
+// logged or timeout has passed since the first repeat, a "previous
+// message repeated N times" record is written, through the same
+// formatters, with the severity, call site and fields of the repeated
+// record and the count in a "repeated" field. A timeout of 0 turns it
+// off. FATAL and PANIC records are never held back.
+// Example:
+//   log.SetDedup(30 * time.Second)
+func (l *FactorLog) SetDedup(timeout time.Duration) {
+	l = l.cfg()
+	var d *deduper
+	if timeout > 0 {
+		d = &deduper{l: l, timeout: timeout}
+	}
+
+	l.mu.Lock()
+	old, _ := l.dedup.Load().(*deduper)
+	l.dedup.Store(d)
+	l.setOwn(ownDedup)
+	l.mu.Unlock()
+
+	if old != nil {
+		old.flush()
+	}
+}
+
+func (l *FactorLog) loadDedup() *deduper {
+	d, _ := l.owner(ownDedup).dedup.Load().(*deduper)
+	return d
+}
+
+// check reports whether a record should be written. A record that
+// differs from the last one first writes the notice for the repeats
+// of the last one, if any.
+func (d *deduper) check(context *LogContext) bool {
+	message := formatMessage(*context)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	last := &d.last
+	if context.Severity&(FATAL|PANIC) == 0 && d.message == message &&
+		last.Severity == context.Severity && last.File == context.File &&
+		last.Line == context.Line && last.Name == context.Name &&
+		reflect.DeepEqual(last.Fields, context.Fields) {
+		d.repeated++
+		if d.timer == nil {
+			d.timer = time.AfterFunc(d.timeout, d.flush)
+		}
+		return false
+	}
+
+	d.writeRepeated()
+	d.last = *context
+	d.message = message
+	return true
+}
+
+// flush writes the notice for held back repeats, if any.
+func (d *deduper) flush() {
+	d.mu.Lock()
+	d.writeRepeated()
+	d.mu.Unlock()
+}
+
+// writeRepeated writes the notice for held back repeats, if any.
+// Must be called with d.mu held.
+func (d *deduper) writeRepeated() {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if d.repeated == 0 {
+		return
+	}
+
+	context := d.last
+	context.Time = time.Now()
+	context.Format = nil
+	context.Args = []interface{}{fmt.Sprintf("previous message repeated %d times", d.repeated)}
+	context.Fields = append(context.Fields[:len(context.Fields):len(context.Fields)], Field{"repeated", d.repeated})
+	d.repeated = 0
+
+	if sinks, _ := d.l.sinksFor(context.Severity); sinks != nil {
+		d.l.dispatch(sinks, context, nil)
+	}
+}
+
+// SetDedup sets the duplicate suppression of the standard logger.
+// See FactorLog.SetDedup().
+func SetDedup(timeout time.Duration) {
+	std.SetDedup(timeout)
+}
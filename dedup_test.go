@@ -0,0 +1,119 @@
+package factorlog
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedBuffer is a bytes.Buffer that can be read while a timer
+// goroutine writes to it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestDedup(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(buf, NewStdFormatter("%{SEVERITY} %{Message} %{Fields}"))
+	log.SetDedup(time.Hour)
+
+	for i := 0; i < 4; i++ {
+		log.Error("disk full")
+	}
+	for i := 0; i < 2; i++ {
+		log.Warn("disk full")
+	}
+	log.Warn("other")
+
+	expect := "ERROR disk full \nERROR previous message repeated 3 times repeated=3\nWARN disk full \nWARN previous message repeated 1 times repeated=1\nWARN other \n"
+	if buf.String() != expect {
+		t.Fatalf("expected %#v, got %#v", expect, buf.String())
+	}
+}
+
+func TestDedupCallSite(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(buf, NewStdFormatter("%{Message}"))
+	log.SetDedup(time.Hour)
+
+	log.Info("hey")
+	log.Info("hey")
+	if buf.String() != "hey\nhey\n" {
+		t.Fatalf("expected records from different lines to be written, got %#v", buf.String())
+	}
+}
+
+func TestDedupFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(buf, NewStdFormatter("%{Message} %{Fields}"))
+	log.SetDedup(time.Hour)
+
+	for _, user := range []string{"a", "b", "b"} {
+		log.With("user", user).Info("login")
+	}
+	log.Info("other")
+
+	expect := "login user=a\nlogin user=b\nprevious message repeated 1 times user=b repeated=1\nother \n"
+	if buf.String() != expect {
+		t.Fatalf("expected records with different fields to be written, got %#v", buf.String())
+	}
+}
+
+func TestDedupTimeout(t *testing.T) {
+	buf := &lockedBuffer{}
+	log := New(buf, NewStdFormatter("%{Message}"))
+	log.SetDedup(10 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		log.Info("hey")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for buf.String() != "hey\nprevious message repeated 2 times\n" {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the repeats to be written after the timeout, got %#v", buf.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Close writes what is held back.
+	for i := 0; i < 2; i++ {
+		log.Info("there")
+	}
+	log.Close()
+	if buf.String() != "hey\nprevious message repeated 2 times\nthere\nprevious message repeated 1 times\n" {
+		t.Fatalf("expected Close to write the held back repeats, got %#v", buf.String())
+	}
+}
+
+func TestDedupInherit(t *testing.T) {
+	t.Cleanup(func() { forgetNamed("testdedup") })
+	buf := &bytes.Buffer{}
+	parent := Named("testdedup")
+	child := Named("testdedup.child")
+	parent.SetOutput(buf)
+	parent.SetFormatter(NewStdFormatter("%{Message}"))
+	child.SetDedup(time.Hour)
+
+	for i := 0; i < 3; i++ {
+		child.Info("hey")
+	}
+	child.Inherit()
+	expect := "hey\nprevious message repeated 2 times\n"
+	if buf.String() != expect {
+		t.Fatalf("expected Inherit to flush the held back repeats, got %#v", buf.String())
+	}
+}
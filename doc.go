@@ -1,7 +1,7 @@
 // FactorLog is a logging infrastructure for Go that provides numerous
 // logging functions for whatever your style may be. It could easily
-// be a replacement for Go's log in the standard library (though it
-// doesn't support functions such as `SetFlags()`).
+// be a replacement for Go's log in the standard library, including
+// functions such as `SetFlags()` and `SetPrefix()`.
 //
 // Basic usage:
 //   import log "github.com/kdar/factorlog"
@@ -56,6 +56,9 @@
 //   %{Message} - The message.
 //   %{SafeMessage} - Safe message. It will escape any character below ASCII 32. This helps prevent
 //                    attacks like using 0x08 to backspace log entries.
+//   %{Fields} - All fields added with With(), as space separated key=value pairs.
+//   %{Field "<key>"} - The value of a single field (e.g. %{Field "request_id"}).
+//   %{Name} - The name of the logger, for loggers created by Named() (e.g. db.pool).
 //
 // Example colors (see https://github.com/mgutz/ansi for more examples):
 //   Added to mgutz/ansi:
@@ -0,0 +1,133 @@
+package factorlog
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// WriteError describes a record that couldn't be written to a sink.
+// It is passed to the handler set with SetErrorHandler().
+type WriteError struct {
+	Err     error      // the error returned by the sink's writer
+	Sink    *Sink      // the sink that failed
+	Context LogContext // the record
+}
+
+func (e *WriteError) Error() string {
+	return "factorlog: write failed: " + e.Err.Error()
+}
+
+// Unwrap returns the error returned by the sink's writer.
+func (e *WriteError) Unwrap() error {
+	return e.Err
+}
+
+// onWriteError holds what a logger does when a sink fails to write.
+type onWriteError struct {
+	handler  func(*WriteError)
+	fallback *fallbackWriter
+}
+
+// fallbackWriter serializes writes to a fallback, which may be shared
+// by several sinks that fail at once.
+type fallbackWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// SetErrorHandler sets a function that is called each time a sink of
+// this logger fails to write a record, after the record has been
+// written to the fallback writer, if there is one. It is called from
+// the goroutine that wrote the record, which is the background
+// goroutine for an asynchronous logger. It must not log to this
+// logger, which would likely fail again. A nil handler removes it.
+// Example:
+//   log.SetErrorHandler(func(err *factorlog.WriteError) {
+//     metrics.Inc("log_write_errors")
+//   })
+func (l *FactorLog) SetErrorHandler(handler func(*WriteError)) {
+	l = l.cfg()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	on := l.loadOnWriteError()
+	l.onError.Store(&onWriteError{handler: handler, fallback: on.fallback})
+	l.setOwn(ownErrors)
+}
+
+// SetFallback sets a writer that records are written to, already
+// formatted, when a sink of this logger fails to write them. A nil
+// writer removes it.
+// Example:
+//   log.SetFallback(os.Stderr)
+func (l *FactorLog) SetFallback(w io.Writer) {
+	l = l.cfg()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	on := l.loadOnWriteError()
+	var fallback *fallbackWriter
+	if w != nil {
+		fallback = &fallbackWriter{w: w}
+	}
+	l.onError.Store(&onWriteError{handler: on.handler, fallback: fallback})
+	l.setOwn(ownErrors)
+}
+
+// WriteErrors returns the number of times a sink of this logger failed
+// to write a record. A named logger that inherits its error handling
+// shares the count of its parent.
+func (l *FactorLog) WriteErrors() uint64 {
+	return atomic.LoadUint64(&l.cfg().owner(ownErrors).writeErrors)
+}
+
+func (l *FactorLog) loadOnWriteError() *onWriteError {
+	on, _ := l.owner(ownErrors).onError.Load().(*onWriteError)
+	if on == nil {
+		return &onWriteError{}
+	}
+	return on
+}
+
+// writeFailed counts a failed write and hands the record to the
+// fallback writer and the error handler. b and stack are the parts of
+// the formatted record that couldn't be written, or nil if they were.
+func (l *FactorLog) writeFailed(s *Sink, context LogContext, b, stack []byte, err error) {
+	atomic.AddUint64(&l.writeErrors, 1)
+
+	on, _ := l.onError.Load().(*onWriteError)
+	if on == nil {
+		return
+	}
+
+	if f := on.fallback; f != nil {
+		f.mu.Lock()
+		if b != nil {
+			f.w.Write(b)
+		}
+		if stack != nil {
+			f.w.Write(stack)
+		}
+		f.mu.Unlock()
+	}
+
+	if on.handler != nil {
+		on.handler(&WriteError{Err: err, Sink: s, Context: context})
+	}
+}
+
+// SetErrorHandler sets the write error handler of the standard logger.
+// See FactorLog.SetErrorHandler().
+func SetErrorHandler(handler func(*WriteError)) {
+	std.SetErrorHandler(handler)
+}
+
+// SetFallback sets the fallback writer of the standard logger.
+// See FactorLog.SetFallback().
+func SetFallback(w io.Writer) {
+	std.SetFallback(w)
+}
+
+// WriteErrors returns the number of failed writes of the standard logger.
+func WriteErrors() uint64 {
+	return std.WriteErrors()
+}
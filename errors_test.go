@@ -0,0 +1,83 @@
+package factorlog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+var errDiskFull = errors.New("disk full")
+
+// failWriter fails every write after the first ok ones.
+type failWriter struct {
+	ok int
+}
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if w.ok > 0 {
+		w.ok--
+		return len(p), nil
+	}
+	return 0, errDiskFull
+}
+
+func TestWriteErrors(t *testing.T) {
+	log := New(&failWriter{}, NewStdFormatter("%{SEVERITY} %{Message}"))
+	fallback := &bytes.Buffer{}
+	log.SetFallback(fallback)
+
+	var handled []*WriteError
+	log.SetErrorHandler(func(err *WriteError) {
+		handled = append(handled, err)
+	})
+
+	if err := log.Output(INFO, 1, "hello"); err != errDiskFull {
+		t.Fatalf("expected Output to return the write error, got %v", err)
+	}
+	log.Error("world")
+
+	if fallback.String() != "INFO hello\nERROR world\n" {
+		t.Fatalf("expected the records in the fallback, got %#v", fallback.String())
+	}
+	if len(handled) != 2 || !errors.Is(handled[1], errDiskFull) || handled[1].Context.Severity != ERROR || handled[1].Sink != log.Sinks()[0] {
+		t.Fatalf("expected the handler to be called for each failed write, got %v", handled)
+	}
+	if log.WriteErrors() != 2 || log.Sinks()[0].WriteErrors() != 2 {
+		t.Fatalf("expected 2 write errors, got %d and %d", log.WriteErrors(), log.Sinks()[0].WriteErrors())
+	}
+
+	// Removing the fallback and handler still counts errors.
+	log.SetFallback(nil)
+	log.SetErrorHandler(nil)
+	log.Info("again")
+	if log.WriteErrors() != 3 || len(handled) != 2 || fallback.Len() != len("INFO hello\nERROR world\n") {
+		t.Fatal("expected only the error count to change")
+	}
+}
+
+func TestWriteErrorsStack(t *testing.T) {
+	// The record is written, but the stack after it isn't.
+	log := New(&failWriter{ok: 1}, NewStdFormatter("%{Message}"))
+	fallback := &bytes.Buffer{}
+	log.SetFallback(fallback)
+	log.Stack("stack")
+	if log.WriteErrors() != 1 || bytes.Contains(fallback.Bytes(), []byte("stack\n")) || !bytes.Contains(fallback.Bytes(), []byte("errors_test.go:")) {
+		t.Fatalf("expected only the stack to go to the fallback, got %d errors and %q", log.WriteErrors(), fallback.String())
+	}
+}
+
+func TestWriteErrorsAsyncNamed(t *testing.T) {
+	t.Cleanup(func() { forgetNamed("testwriteerrors") })
+	parent := Named("testwriteerrors")
+	child := Named("testwriteerrors.child")
+	parent.SetOutput(&failWriter{})
+	fallback := &bytes.Buffer{}
+	parent.SetFallback(fallback)
+	parent.SetAsync(10, OverflowBlock)
+
+	child.Info("hey")
+	parent.Close()
+	if fallback.String() == "" || child.WriteErrors() != 1 || parent.WriteErrors() != 1 {
+		t.Fatalf("expected the child to use the parent's error handling, got %q and %d errors", fallback.String(), parent.WriteErrors())
+	}
+}
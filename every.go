@@ -0,0 +1,166 @@
+package factorlog
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// siteCounter counts the calls made from one call site to EveryN,
+// FirstN or Every.
+type siteCounter struct {
+	n    uint64 // calls so far
+	last int64  // UnixNano of the last call let through by Every
+}
+
+// callSites holds a siteCounter per call site. Like the vmodule
+// cache, the map is copied on write, so looking up a known call site
+// doesn't take a lock.
+var callSites struct {
+	mu    sync.Mutex
+	sites atomic.Value // map[uintptr]*siteCounter
+}
+
+// siteCounterAt returns the counter of the call site calldepth frames
+// above the caller of siteCounterAt, or nil if it can't be found.
+func siteCounterAt(calldepth int) *siteCounter {
+	var pcs [1]uintptr
+	// skip runtime.Callers, siteCounterAt and calldepth more frames
+	if runtime.Callers(calldepth+2, pcs[:]) == 0 {
+		return nil
+	}
+	pc := pcs[0]
+
+	sites, _ := callSites.sites.Load().(map[uintptr]*siteCounter)
+	if c, ok := sites[pc]; ok {
+		return c
+	}
+
+	callSites.mu.Lock()
+	defer callSites.mu.Unlock()
+	old, _ := callSites.sites.Load().(map[uintptr]*siteCounter)
+	if c, ok := old[pc]; ok {
+		return c
+	}
+	c := &siteCounter{}
+	sites = make(map[uintptr]*siteCounter, len(old)+1)
+	for k, v := range old {
+		sites[k] = v
+	}
+	sites[pc] = c
+	callSites.sites.Store(sites)
+	return c
+}
+
+// resetCallSites forgets the counts of every call site. It is used by
+// tests.
+func resetCallSites() {
+	callSites.mu.Lock()
+	callSites.sites.Store(map[uintptr]*siteCounter{})
+	callSites.mu.Unlock()
+}
+
+// everyN counts a call and reports whether it is the 1st, n+1th,
+// 2n+1th... one.
+func (c *siteCounter) everyN(n int) bool {
+	count := atomic.AddUint64(&c.n, 1)
+	return n <= 1 || (count-1)%uint64(n) == 0
+}
+
+// firstN counts a call and reports whether it is one of the first n.
+func (c *siteCounter) firstN(n int) bool {
+	if atomic.LoadUint64(&c.n) >= uint64(n) {
+		return false
+	}
+	return atomic.AddUint64(&c.n, 1) <= uint64(n)
+}
+
+// every reports whether d has passed since the last call it let
+// through, which is always the case for the first call.
+func (c *siteCounter) every(d time.Duration) bool {
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&c.last)
+	if last != 0 && now-last < int64(d) {
+		return false
+	}
+	return atomic.CompareAndSwapInt64(&c.last, last, now)
+}
+
+// EveryN returns a Verbose that logs the 1st call from this call site,
+// then every nth one, like glog's LOG_EVERY_N. Calls are counted per
+// call site, whatever logger they are made on.
+// Example:
+//   for _, item := range items {
+//     log.EveryN(100).Infof("processing %v", item)
+//   }
+func (l *FactorLog) EveryN(n int) Verbose {
+	c := siteCounterAt(1)
+	return Verbose{c != nil && c.everyN(n), l}
+}
+
+// FirstN returns a Verbose that only logs the first n calls from this
+// call site, like glog's LOG_FIRST_N.
+// Example:
+//   log.FirstN(5).Warn("deprecated option used")
+func (l *FactorLog) FirstN(n int) Verbose {
+	c := siteCounterAt(1)
+	return Verbose{c != nil && c.firstN(n), l}
+}
+
+// Every returns a Verbose that logs a call from this call site at
+// most once every d.
+// Example:
+//   log.Every(10 * time.Second).Error("connection refused")
+func (l *FactorLog) Every(d time.Duration) Verbose {
+	c := siteCounterAt(1)
+	return Verbose{c != nil && c.every(d), l}
+}
+
+// EveryN is like FactorLog.EveryN, for a call that also depends on
+// the verbosity. Calls are only counted if b is true.
+// Example:
+//   log.V(2).EveryN(100).Info("some info")
+func (b Verbose) EveryN(n int) Verbose {
+	if !b.True {
+		return b
+	}
+	c := siteCounterAt(1)
+	return Verbose{c != nil && c.everyN(n), b.logger}
+}
+
+// FirstN is like FactorLog.FirstN. Calls are only counted if b is true.
+func (b Verbose) FirstN(n int) Verbose {
+	if !b.True {
+		return b
+	}
+	c := siteCounterAt(1)
+	return Verbose{c != nil && c.firstN(n), b.logger}
+}
+
+// Every is like FactorLog.Every. Calls are only counted if b is true.
+func (b Verbose) Every(d time.Duration) Verbose {
+	if !b.True {
+		return b
+	}
+	c := siteCounterAt(1)
+	return Verbose{c != nil && c.every(d), b.logger}
+}
+
+// EveryN calls EveryN on the standard logger.
+func EveryN(n int) Verbose {
+	c := siteCounterAt(1)
+	return Verbose{c != nil && c.everyN(n), std}
+}
+
+// FirstN calls FirstN on the standard logger.
+func FirstN(n int) Verbose {
+	c := siteCounterAt(1)
+	return Verbose{c != nil && c.firstN(n), std}
+}
+
+// Every calls Every on the standard logger.
+func Every(d time.Duration) Verbose {
+	c := siteCounterAt(1)
+	return Verbose{c != nil && c.every(d), std}
+}
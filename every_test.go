@@ -0,0 +1,50 @@
+package factorlog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestEveryN(t *testing.T) {
+	t.Cleanup(resetCallSites)
+	buf := &bytes.Buffer{}
+	log := New(buf, NewStdFormatter("%{Message}"))
+
+	for i := 0; i < 7; i++ {
+		log.EveryN(3).Infof("every %d", i)
+		log.FirstN(2).Warnf("first %d", i)
+	}
+	expect := "every 0\nfirst 0\nfirst 1\nevery 3\nevery 6\n"
+	if buf.String() != expect {
+		t.Fatalf("expected %#v, got %#v", expect, buf.String())
+	}
+
+	// The verbosity is checked first, and calls it fails aren't counted.
+	buf.Reset()
+	for i := 0; i < 4; i++ {
+		log.V(Level(1 - i%2)).EveryN(2).Info(i)
+	}
+	if buf.String() != "1\n" {
+		t.Fatalf("expected only counted calls to be logged, got %#v", buf.String())
+	}
+}
+
+func TestEvery(t *testing.T) {
+	t.Cleanup(resetCallSites)
+	buf := &bytes.Buffer{}
+	log := New(buf, NewStdFormatter("%{Message}"))
+
+	for i := 0; i < 3; i++ {
+		log.Every(time.Hour).Error(i)
+	}
+	for i := 0; i < 2; i++ {
+		if i > 0 {
+			time.Sleep(2 * time.Millisecond)
+		}
+		log.Every(time.Millisecond).Error("ms", i)
+	}
+	if buf.String() != "0\nms0\nms1\n" {
+		t.Fatalf("expected one record per duration, got %#v", buf.String())
+	}
+}
@@ -6,16 +6,19 @@ import (
 	"os"
 
 	"github.com/kdar/factorlog"
-	"github.com/kdar/factorlog-contrib/gelf"
 )
 
 func main() {
-	log := factorlog.New(os.Stdout, gelf.NewGELFFormatter())
+	log := factorlog.New(os.Stdout, factorlog.NewGELFFormatter())
 	log.Print("GELF formatter")
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Print("GELF: client connected", gelf.Extra{"_hello": "there"}, r)
+		log.With("hello", "there", "remote_addr", r.RemoteAddr).Print("GELF: client connected")
 	}))
 
 	http.Get(server.URL)
+
+	// To send records to Graylog instead:
+	//   w, err := factorlog.NewGELFWriter("udp", "graylog:12201")
+	//   log := factorlog.New(w, factorlog.NewGELFFormatter())
 }
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/kdar/factorlog"
+	"os"
+)
+
+func main() {
+	frmt := `%{Color "red" "ERROR"}%{Color "yellow" "WARN"}%{Color "green" "INFO"}[%{Date} %{Time}] [%{SEVERITY}] %{Message}%{Color "reset"}`
+	console := factorlog.NewSink(os.Stderr, factorlog.NewStdFormatter(frmt))
+	console.SetMinMaxSeverity(factorlog.INFO, factorlog.PANIC)
+
+	json := factorlog.NewJSONFormatter()
+	json.Caller = true
+	everything := factorlog.NewSink(os.Stdout, json)
+
+	log := factorlog.NewMulti(console, everything)
+	log.Error("Sinks: goes to both")
+	log.Info("Sinks: goes to both")
+	log.Debug("Sinks: only goes to the JSON sink")
+}
@@ -99,34 +99,100 @@ type Logger interface {
 }
 
 // FactorLog is a logging object that outputs data to an io.Writer.
-// Each write is threadsafe.
+// Each write is threadsafe. Checking whether a record is enabled
+// doesn't take a lock, and records are formatted concurrently when
+// the formatter allows it; only the writes themselves are serialized.
 type FactorLog struct {
-	mu         sync.Mutex // ensures atomic writes; protects the following fields
-	out        io.Writer  // destination for output
-	formatter  Formatter
+	writeErrors uint64 // first for 64-bit alignment of atomic access
+
+	mu         sync.Mutex   // serializes changes to sinks and async
+	sinks      atomic.Value // []*Sink; destinations for output, the first is set by New
+	async      atomic.Value // *asyncQueue; set when records are written in the background
 	verbosity  Level
 	severities Severity
+	vmodule    atomic.Value // *vmodule; per file verbosity set by SetVModule
+	onError    atomic.Value // *onWriteError; set by SetErrorHandler and SetFallback
+	printSev   Severity     // severity of Print; DEBUG if not set
+	sampling   atomic.Value // *sampler; set by SetSampling
+	dedup      atomic.Value // *deduper; set by SetDedup
+	hooks      atomic.Value // *hookSet; set by AddHook and AddWriteHook
+	filters    atomic.Value // *filterSet; set by Include and Exclude
+
+	// flags and prefix are the log package style settings last given
+	// to SetFlags and SetPrefix. Guarded by mu.
+	flags  int
+	prefix string
+
+	// name and parent are set on loggers created by Named(). Any
+	// setting not in own is inherited from the parent.
+	name   string
+	parent *FactorLog
+	own    int32
+
+	// base is set on child loggers created by With(). A child
+	// shares the configuration and output of its base.
+	base   *FactorLog
+	fields []Field
 }
 
 // New creates a FactorLog with the given output and format.
 func New(out io.Writer, formatter Formatter) *FactorLog {
-	return &FactorLog{out: out, formatter: formatter, severities: Severity(maxint32)}
+	return NewMulti(NewSink(out, formatter))
 }
 
 // just like Go's log.std
-var std = New(os.Stderr, NewStdFormatter("%{Date} %{Time} %{Message}"))
+var std = New(os.Stderr, NewStdFormatter(defaultFormat))
+
+// With returns a child logger that adds the given key/value pairs to
+// every record it logs. The pairs are passed to the formatter through
+// LogContext.Fields. Arguments are alternating keys and values, though
+// a Field may be passed in place of a pair.
+// The child shares its configuration and output with l, so changing
+// the verbosity, severities, output or formatter of one changes both.
+// Example:
+//   reqLog := log.With("request_id", id, "user", name)
+//   reqLog.Info("handling request")
+func (l *FactorLog) With(keyvals ...interface{}) *FactorLog {
+	fields := make([]Field, 0, len(l.fields)+(len(keyvals)+1)/2)
+	fields = append(fields, l.fields...)
+	fields = append(fields, makeFields(keyvals)...)
+	return &FactorLog{base: l.cfg(), fields: fields}
+}
+
+// cfg returns the logger that holds the configuration and output
+// for l.
+func (l *FactorLog) cfg() *FactorLog {
+	if l.base != nil {
+		return l.base
+	}
+	return l
+}
 
 // Sets the verbosity level of this log. Use IsV() or V() to
 // utilize verbosity.
 func (l *FactorLog) SetVerbosity(level Level) {
+	l = l.cfg()
 	l.verbosity.set(level)
+	l.setOwn(ownVerbosity)
+}
+
+// Verbosity returns the verbosity level of this log.
+func (l *FactorLog) Verbosity() Level {
+	return l.cfg().owner(ownVerbosity).verbosity.get()
+}
+
+// Severities returns the severities this log will output for.
+func (l *FactorLog) Severities() Severity {
+	return l.cfg().owner(ownSeverities).severities.get()
 }
 
 // SetSeverities sets which severities this log will output for.
 // Example:
 //   l.SetSeverities(INFO|DEBUG)
 func (l *FactorLog) SetSeverities(sev Severity) {
+	l = l.cfg()
 	l.severities.set(sev)
+	l.setOwn(ownSeverities)
 }
 
 // SetMinMaxSeverity sets the minimum and maximum severities this
@@ -134,6 +200,12 @@ func (l *FactorLog) SetSeverities(sev Severity) {
 // Example:
 //   l.SetMinMaxSeverity(INFO, ERROR)
 func (l *FactorLog) SetMinMaxSeverity(min Severity, max Severity) {
+	l.SetSeverities(minMaxSeverities(min, max))
+}
+
+// minMaxSeverities returns a mask of all the severities
+// between min and max inclusive.
+func minMaxSeverities(min Severity, max Severity) Severity {
 	if min > max || max < min {
 		min, max = max, min
 	}
@@ -151,7 +223,7 @@ func (l *FactorLog) SetMinMaxSeverity(min Severity, max Severity) {
 		sev |= s
 	}
 
-	l.severities.set(sev)
+	return sev
 }
 
 // Output will write to the writer with the given severity, calldepth,
@@ -162,12 +234,15 @@ func (l *FactorLog) Output(sev Severity, calldepth int, v ...interface{}) error
 }
 
 func (l *FactorLog) output(sev Severity, calldepth int, format *string, v ...interface{}) error {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	fields := l.fields
+	l = l.cfg()
 
-	if sev&l.severities.get() == 0 {
+	sinks, needCaller := l.sinksFor(sev)
+	if sinks == nil {
 		return nil
 	}
+	dedup := l.loadDedup()
+	filters := l.loadFilters()
 
 	context := LogContext{
 		Time:     time.Now(),
@@ -175,11 +250,11 @@ func (l *FactorLog) output(sev Severity, calldepth int, format *string, v ...int
 		Pid:      pid,
 		Format:   format,
 		Args:     v,
+		Fields:   fields,
+		Name:     l.name,
 	}
 
-	if l.formatter.ShouldRuntimeCaller() {
-		// release lock while getting caller info - it's expensive.
-		l.mu.Unlock()
+	if needCaller || dedup != nil || (filters != nil && filters.caller) || l.loadSampler().needsCaller() {
 		var ok bool
 		pc, file, line, ok := runtime.Caller(calldepth)
 		if !ok {
@@ -192,34 +267,125 @@ func (l *FactorLog) output(sev Severity, calldepth int, format *string, v ...int
 			}
 		}
 
+		context.PC = pc
 		context.File = file
 		context.Line = line
-
-		l.mu.Lock()
 	}
 
-	_, err := l.out.Write(l.formatter.Format(context))
+	if !l.admit(&context, filters, dedup) {
+		return nil
+	}
 
-	// If severity is STACK, output the stack.
+	// If severity is STACK, output the stack after the record.
+	var stack []byte
 	if sev == STACK {
-		l.out.Write(GetStack(calldepth + 1))
+		stack = GetStack(calldepth + 1)
+	}
+
+	return l.dispatch(sinks, context, stack)
+}
+
+// sinksFor returns the sinks of l if l and at least one of them accept
+// records of severity sev, or nil otherwise, and whether any sink that
+// accepts it needs the caller. runtime.Caller is only called when one does.
+func (l *FactorLog) sinksFor(sev Severity) ([]*Sink, bool) {
+	if sev&l.owner(ownSeverities).severities.get() == 0 {
+		return nil, false
+	}
+
+	sinks := l.loadSinks()
+	accepted := false
+	needCaller := false
+	for _, s := range sinks {
+		if sev&s.severities.get() != 0 {
+			accepted = true
+			if s.Formatter().ShouldRuntimeCaller() {
+				needCaller = true
+			}
+		}
+	}
+
+	if !accepted {
+		return nil, false
+	}
+	return sinks, needCaller
+}
+
+// admit reports whether a record should be written, once its context
+// is filled in. filters and dedup are the results of l.loadFilters()
+// and l.loadDedup().
+func (l *FactorLog) admit(context *LogContext, filters *filterSet, dedup *deduper) bool {
+	if filters != nil && !filters.match(context) {
+		return false
+	}
+	if !l.runHooks(context) {
+		return false
 	}
+	if s := l.loadSampler(); s != nil && !s.sample(context) {
+		return false
+	}
+	if dedup != nil && !dedup.check(context) {
+		return false
+	}
+	return true
+}
+
+// dispatch hands a record to the asynchronous queue of l, or writes
+// it to the sinks if l isn't asynchronous.
+func (l *FactorLog) dispatch(sinks []*Sink, context LogContext, stack []byte) error {
+	r := asyncRecord{context, stack, sinks, l.owner(ownErrors), l.writeHooks()}
+	if q := l.loadAsync(); q != nil {
+		q.push(r)
+		return nil
+	}
+
+	return r.write()
+}
 
-	return err
+func (l *FactorLog) loadSinks() []*Sink {
+	sinks, _ := l.owner(ownSinks).sinks.Load().([]*Sink)
+	return sinks
+}
+
+func (l *FactorLog) loadAsync() *asyncQueue {
+	q, _ := l.owner(ownAsync).async.Load().(*asyncQueue)
+	return q
 }
 
 // SetOutput sets the output destination for this logger.
+// If the logger has several sinks, this sets the output of the first.
+// The sink is changed in place, so this affects other loggers that
+// share it. SetOutput waits for a record being written to the old
+// writer, so it can be closed once SetOutput returns.
 func (l *FactorLog) SetOutput(w io.Writer) {
+	l = l.cfg()
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.out = w
+	l.claimSinks()
+	l.replaceFirstSink(w, nil)
 }
 
 // SetFormatter sets the formatter for this logger.
+// If the logger has several sinks, this sets the formatter of the
+// first, in place like SetOutput.
 func (l *FactorLog) SetFormatter(f Formatter) {
+	l = l.cfg()
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.formatter = f
+	l.claimSinks()
+	l.replaceFirstSink(nil, f)
+}
+
+// replaceFirstSink changes the first sink to use w or f instead, if
+// they aren't nil, or adds a sink if there is none. Must be called
+// with l.mu held.
+func (l *FactorLog) replaceFirstSink(w io.Writer, f Formatter) {
+	sinks := l.loadSinks()
+	if len(sinks) == 0 {
+		l.sinks.Store([]*Sink{NewSink(w, f)})
+		return
+	}
+	sinks[0].replace(w, f)
 }
 
 // IsV tests whether the verbosity is of a certain level.
@@ -229,11 +395,7 @@ func (l *FactorLog) SetFormatter(f Formatter) {
 //      log.Info("some info")
 //    }
 func (l *FactorLog) IsV(level Level) bool {
-	if l.verbosity.get() >= level {
-		return true
-	}
-
-	return false
+	return l.isV(level, 1)
 }
 
 // V tests whether the verbosity is of a certain level,
@@ -244,7 +406,7 @@ func (l *FactorLog) IsV(level Level) bool {
 // Example:
 //   log.V(2).Info("some info")
 func (l *FactorLog) V(level Level) Verbose {
-	if l.verbosity.get() >= level {
+	if l.isV(level, 1) {
 		return Verbose{true, l}
 	}
 
@@ -365,58 +527,72 @@ func (l *FactorLog) Log(sev Severity, v ...interface{}) {
 }
 
 // Print calls l.output to print to the logger. Uses fmt.Sprint.
+// It logs with severity DEBUG, or the one set by SetPrintSeverity.
 func (l *FactorLog) Print(v ...interface{}) {
-	l.output(DEBUG, 2, nil, v...)
+	l.output(l.PrintSeverity(), 2, nil, v...)
 }
 
 // Print calls l.output to print to the logger. Uses fmt.Sprintf.
 func (l *FactorLog) Printf(format string, v ...interface{}) {
-	l.output(DEBUG, 2, &format, v...)
+	l.output(l.PrintSeverity(), 2, &format, v...)
 }
 
 // Println calls l.output to print to the logger. Uses fmt.Sprint.
 // This is more of a convenience function. If you really want
 // to output an extra newline at the end, just append \n.
 func (l *FactorLog) Println(v ...interface{}) {
-	l.output(DEBUG, 2, nil, v...)
+	l.output(l.PrintSeverity(), 2, nil, v...)
 }
 
 // Fatal is equivalent to Print() followed by a call to os.Exit(1).
+// The logger is flushed and the exit hooks are run before exiting.
+// See AddExitHook() and SetExitFunc().
 func (l *FactorLog) Fatal(v ...interface{}) {
 	l.output(FATAL, 2, nil, v...)
-	os.Exit(1)
+	l.exit()
 }
 
 // Fatalf is equivalent to Printf() followed by a call to os.Exit(1).
 func (l *FactorLog) Fatalf(format string, v ...interface{}) {
 	l.output(FATAL, 2, &format, v...)
-	os.Exit(1)
+	l.exit()
 }
 
 // Fatalln is equivalent to Println() followed by a call to os.Exit(1).
 func (l *FactorLog) Fatalln(v ...interface{}) {
 	l.output(FATAL, 2, nil, v...)
-	os.Exit(1)
+	l.exit()
 }
 
 // Panic is equivalent to Print() followed by a call to panic().
+// The logger is flushed before panicking, so the record isn't lost if
+// the panic ends the program.
 func (l *FactorLog) Panic(v ...interface{}) {
 	l.output(PANIC, 2, nil, v...)
+	l.Flush()
 	panic(fmt.Sprint(v...))
 }
 
 // Panicf is equivalent to Printf() followed by a call to panic().
 func (l *FactorLog) Panicf(format string, v ...interface{}) {
 	l.output(PANIC, 2, &format, v...)
+	l.Flush()
 	panic(fmt.Sprintf(format, v...))
 }
 
 // Panicf is equivalent to Printf() followed by a call to panic().
 func (l *FactorLog) Panicln(v ...interface{}) {
 	l.output(PANIC, 2, nil, v...)
+	l.Flush()
 	panic(fmt.Sprint(v...))
 }
 
+// Name returns the name of a logger created by Named(), or an empty
+// string for any other logger.
+func (l *FactorLog) Name() string {
+	return l.cfg().name
+}
+
 // Verbose is a structure that enables syntatic sugar
 // when testing for verbosity and calling a log function.
 // See FactorLog.V().
@@ -567,46 +743,47 @@ func (b Verbose) Log(sev Severity, v ...interface{}) {
 
 func (b Verbose) Print(v ...interface{}) {
 	if b.True {
-		b.logger.output(DEBUG, 2, nil, v...)
+		b.logger.output(b.logger.PrintSeverity(), 2, nil, v...)
 	}
 }
 
 func (b Verbose) Printf(format string, v ...interface{}) {
 	if b.True {
-		b.logger.output(DEBUG, 2, &format, v...)
+		b.logger.output(b.logger.PrintSeverity(), 2, &format, v...)
 	}
 }
 
 func (b Verbose) Println(v ...interface{}) {
 	if b.True {
-		b.logger.output(DEBUG, 2, nil, v...)
+		b.logger.output(b.logger.PrintSeverity(), 2, nil, v...)
 	}
 }
 
 func (b Verbose) Fatal(v ...interface{}) {
 	if b.True {
 		b.logger.output(FATAL, 2, nil, v...)
-		os.Exit(1)
+		b.logger.exit()
 	}
 }
 
 func (b Verbose) Fatalf(format string, v ...interface{}) {
 	if b.True {
 		b.logger.output(FATAL, 2, &format, v...)
-		os.Exit(1)
+		b.logger.exit()
 	}
 }
 
 func (b Verbose) Fatalln(v ...interface{}) {
 	if b.True {
 		b.logger.output(FATAL, 2, nil, v...)
-		os.Exit(1)
+		b.logger.exit()
 	}
 }
 
 func (b Verbose) Panic(v ...interface{}) {
 	if b.True {
 		b.logger.output(PANIC, 2, nil, v...)
+		b.logger.Flush()
 		panic(fmt.Sprint(v...))
 	}
 }
@@ -614,6 +791,7 @@ func (b Verbose) Panic(v ...interface{}) {
 func (b Verbose) Panicf(format string, v ...interface{}) {
 	if b.True {
 		b.logger.output(PANIC, 2, &format, v...)
+		b.logger.Flush()
 		panic(fmt.Sprintf(format, v...))
 	}
 }
@@ -621,20 +799,17 @@ func (b Verbose) Panicf(format string, v ...interface{}) {
 func (b Verbose) Panicln(v ...interface{}) {
 	if b.True {
 		b.logger.output(PANIC, 2, nil, v...)
+		b.logger.Flush()
 		panic(fmt.Sprint(v...))
 	}
 }
 
 func (b Verbose) IsV(level Level) bool {
-	if b.logger.verbosity.get() >= level {
-		return true
-	}
-
-	return false
+	return b.logger.isV(level, 1)
 }
 
 func (b Verbose) V(level Level) Verbose {
-	if b.logger.verbosity.get() >= level {
+	if b.logger.isV(level, 1) {
 		return Verbose{true, b.logger}
 	}
 
@@ -650,16 +825,12 @@ func (b Verbose) SetVerbosity(level Level) {
 
 // SetOutput sets the output destination for the standard logger.
 func SetOutput(w io.Writer) {
-	std.mu.Lock()
-	defer std.mu.Unlock()
-	std.out = w
+	std.SetOutput(w)
 }
 
 // SetFormatter sets the formatter for the standard logger.
 func SetFormatter(f Formatter) {
-	std.mu.Lock()
-	defer std.mu.Unlock()
-	std.formatter = f
+	std.SetFormatter(f)
 }
 
 func SetVerbosity(level Level) {
@@ -674,16 +845,18 @@ func SetMinMaxSeverity(min Severity, max Severity) {
 	std.SetMinMaxSeverity(min, max)
 }
 
-func IsV(level Level) bool {
-	if std.verbosity.get() >= level {
-		return true
-	}
+// With returns a child of the standard logger that adds the given
+// key/value pairs to every record. See FactorLog.With().
+func With(keyvals ...interface{}) *FactorLog {
+	return std.With(keyvals...)
+}
 
-	return false
+func IsV(level Level) bool {
+	return std.isV(level, 1)
 }
 
 func V(level Level) Verbose {
-	if std.verbosity.get() >= level {
+	if std.isV(level, 1) {
 		return Verbose{true, std}
 	}
 
@@ -779,49 +952,53 @@ func Log(sev Severity, v ...interface{}) {
 }
 
 func Print(v ...interface{}) {
-	std.output(DEBUG, 2, nil, v...)
+	std.output(std.PrintSeverity(), 2, nil, v...)
 }
 
 func Printf(format string, v ...interface{}) {
-	std.output(DEBUG, 2, &format, v...)
+	std.output(std.PrintSeverity(), 2, &format, v...)
 }
 
 func Println(v ...interface{}) {
-	std.output(DEBUG, 2, nil, v...)
+	std.output(std.PrintSeverity(), 2, nil, v...)
 }
 
 func Fatal(v ...interface{}) {
 	std.output(FATAL, 2, nil, v...)
-	os.Exit(1)
+	std.exit()
 }
 
 func Fatalf(format string, v ...interface{}) {
 	std.output(FATAL, 2, &format, v...)
-	os.Exit(1)
+	std.exit()
 }
 
 func Fatalln(v ...interface{}) {
 	std.output(FATAL, 2, nil, v...)
-	os.Exit(1)
+	std.exit()
 }
 
 func Panic(v ...interface{}) {
 	std.output(PANIC, 2, nil, v...)
+	std.Flush()
 	panic(fmt.Sprint(v...))
 }
 
 func Panicf(format string, v ...interface{}) {
 	std.output(PANIC, 2, &format, v...)
+	std.Flush()
 	panic(fmt.Sprintf(format, v...))
 }
 
 func Panicln(v ...interface{}) {
 	std.output(PANIC, 2, nil, v...)
+	std.Flush()
 	panic(fmt.Sprint(v...))
 }
 
 func init() {
 	pid = os.Getpid()
+	std.flags = LstdFlags
 }
 
 // Creates a logger that outputs to nothing
@@ -7,6 +7,7 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -155,6 +156,70 @@ func TestChainedVerbosity(t *testing.T) {
 
 }
 
+func TestWith(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, NewStdFormatter("%{Message} %{Fields}"))
+	child := l.With("request_id", "abc").With("user", "bob", "dangling")
+
+	child.Info("hey")
+	expect := "hey request_id=abc user=bob dangling=(MISSING)\n"
+	if buf.String() != expect {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", expect, buf.String())
+	}
+
+	// The parent must not pick up the child's fields.
+	buf.Reset()
+	l.Info("hey")
+	if buf.String() != "hey \n" {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", "hey \n", buf.String())
+	}
+
+	// The child shares the parent's configuration.
+	buf.Reset()
+	l.SetSeverities(ERROR)
+	child.Info("should not appear")
+	if buf.Len() > 0 {
+		t.Fatal("severities set on the parent should apply to the child")
+	}
+
+	buf2 := &bytes.Buffer{}
+	child.SetOutput(buf2)
+	l.Error("hey")
+	if buf2.String() != "hey \n" {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", "hey \n", buf2.String())
+	}
+}
+
+func TestConcurrentOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, NewStdFormatter("%{SEVERITY} %{Time} %{File}:%{Line} %{SafeMessage}"))
+	l.SetSeverities(INFO)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.Infof("goroutine %d line %d", i, j)
+				l.Trace("disabled")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 800 {
+		t.Fatalf("expected 800 lines, got %d", len(lines))
+	}
+	r := regexp.MustCompile(`^INFO \d\d:\d\d:\d\d factorlog_test.go:\d+ goroutine \d line \d+$`)
+	for _, line := range lines {
+		if !r.MatchString(line) {
+			t.Fatalf("unexpected line %#v", line)
+		}
+	}
+}
+
 type sevTestType int
 
 const (
@@ -209,7 +274,7 @@ func TestSeverities(t *testing.T) {
 
 // Ensure `std`'s format is correct.
 func TestStdFormat(t *testing.T) {
-	output := std.formatter.Format(fmtTestsContext)
+	output := std.loadSinks()[0].Formatter().Format(fmtTestsContext)
 	expect := "2014-01-08 23:27:14 hello there!\n"
 	if string(output) != expect {
 		t.Fatalf("\nexpected: %#v\ngot:      %#v", expect, string(output))
@@ -233,3 +298,26 @@ func BenchmarkFactorLogBuffer(b *testing.B) {
 		l.Info("hey")
 	}
 }
+
+func BenchmarkFactorLogBufferParallel(b *testing.B) {
+	buf := &bytes.Buffer{}
+	l := New(buf, NewStdFormatter("%{Date} %{Time} %{File}:%{Line}: %{Message}"))
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			l.Info("hey")
+		}
+	})
+}
+
+func BenchmarkFactorLogDisabledParallel(b *testing.B) {
+	buf := &bytes.Buffer{}
+	l := New(buf, NewStdFormatter("%{Date} %{Time} %{File}:%{Line}: %{Message}"))
+	l.SetSeverities(INFO)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			l.Trace("hey")
+		}
+	})
+}
@@ -0,0 +1,272 @@
+package factorlog
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Filter decides whether a record is written, before it is formatted.
+// See FactorLog.Include() and FactorLog.Exclude().
+type Filter interface {
+	// Match reports whether the record matches the filter.
+	Match(context *LogContext) bool
+	// ShouldRuntimeCaller reports whether Match needs the caller's
+	// file, line and function. The caller is only looked up if a
+	// filter or a formatter needs it.
+	ShouldRuntimeCaller() bool
+}
+
+// filterSet holds the filters of a logger.
+type filterSet struct {
+	include []Filter
+	exclude []Filter
+	caller  bool // a filter needs the caller
+}
+
+// Include only lets records through if they match at least one of the
+// filters passed to Include. Calling it again adds more filters.
+// Filters run before hooks, sampling and formatting.
+// Example:
+//   myapp, err := factorlog.PackageFilter("myapp/*")
+//   if err != nil {
+//     return err
+//   }
+//   log.Include(myapp)
+func (l *FactorLog) Include(filters ...Filter) {
+	l.addFilters(filters, nil)
+}
+
+// Exclude drops records that match any of the filters passed to
+// Exclude, even if they are included.
+// Example:
+//   // Silence a noisy package, except for its errors.
+//   noisy, err := factorlog.PackageFilter("github.com/noisy/*")
+//   if err != nil {
+//     return err
+//   }
+//   log.Exclude(factorlog.AndFilter(
+//     noisy,
+//     factorlog.NotFilter(factorlog.SeverityFilter(factorlog.ERROR|factorlog.CRITICAL)),
+//   ))
+func (l *FactorLog) Exclude(filters ...Filter) {
+	l.addFilters(nil, filters)
+}
+
+// ClearFilters removes the filters of this logger. A named logger
+// stops inheriting the filters of its parent.
+func (l *FactorLog) ClearFilters() {
+	l = l.cfg()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.filters.Store((*filterSet)(nil))
+	l.setOwn(ownFilters)
+}
+
+func (l *FactorLog) addFilters(include, exclude []Filter) {
+	l = l.cfg()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fs := &filterSet{}
+	if old := l.loadFilters(); old != nil {
+		*fs = *old
+	}
+	fs.include = append(fs.include[:len(fs.include):len(fs.include)], include...)
+	fs.exclude = append(fs.exclude[:len(fs.exclude):len(fs.exclude)], exclude...)
+	for _, f := range fs.include {
+		fs.caller = fs.caller || f.ShouldRuntimeCaller()
+	}
+	for _, f := range fs.exclude {
+		fs.caller = fs.caller || f.ShouldRuntimeCaller()
+	}
+	l.filters.Store(fs)
+	l.setOwn(ownFilters)
+}
+
+func (l *FactorLog) loadFilters() *filterSet {
+	fs, _ := l.owner(ownFilters).filters.Load().(*filterSet)
+	return fs
+}
+
+// match reports whether a record passes the filters.
+func (fs *filterSet) match(context *LogContext) bool {
+	if len(fs.include) > 0 && !anyFilter(fs.include, context) {
+		return false
+	}
+	return !anyFilter(fs.exclude, context)
+}
+
+func anyFilter(filters []Filter, context *LogContext) bool {
+	for _, f := range filters {
+		if f.Match(context) {
+			return true
+		}
+	}
+	return false
+}
+
+type severityFilter Severity
+
+// SeverityFilter matches records of the given severities.
+func SeverityFilter(sev Severity) Filter {
+	return severityFilter(sev)
+}
+
+func (f severityFilter) Match(context *LogContext) bool {
+	return context.Severity&Severity(f) != 0
+}
+
+func (f severityFilter) ShouldRuntimeCaller() bool {
+	return false
+}
+
+// callerFilter matches a glob against part of the caller.
+type callerFilter struct {
+	pattern string
+	elems   int // number of path elements the pattern matches against, or 0 for all
+	part    func(context *LogContext) string
+}
+
+func newCallerFilter(kind, pattern string, elems int, part func(context *LogContext) string) (Filter, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("factorlog: %s pattern %q: %v", kind, pattern, err)
+	}
+	return &callerFilter{pattern, elems, part}, nil
+}
+
+// PackageFilter matches records logged from packages whose import
+// path matches pattern, a path.Match pattern. Like SetVModule(), the
+// pattern is matched against as many trailing elements of the path
+// as it has, so "db" matches github.com/me/app/db. It returns an error
+// if the pattern is malformed.
+func PackageFilter(pattern string) (Filter, error) {
+	return newCallerFilter("package", pattern, strings.Count(pattern, "/")+1, func(context *LogContext) string {
+		return funcPackage(context.Function)
+	})
+}
+
+// FileFilter matches records logged from source files matching
+// pattern, e.g. "server.go" or "*_test.go", against trailing elements
+// of the file's path like PackageFilter. It returns an error if the
+// pattern is malformed.
+func FileFilter(pattern string) (Filter, error) {
+	return newCallerFilter("file", pattern, strings.Count(pattern, "/")+1, func(context *LogContext) string {
+		return context.File
+	})
+}
+
+// FunctionFilter matches records logged from functions whose name,
+// without the package (e.g. (*Server).serve), matches pattern. It
+// returns an error if the pattern is malformed.
+func FunctionFilter(pattern string) (Filter, error) {
+	return newCallerFilter("function", pattern, 0, func(context *LogContext) string {
+		fn := context.Function
+		if slash := strings.LastIndexByte(fn, '/'); slash >= 0 {
+			fn = fn[slash+1:]
+		}
+		if dot := strings.IndexByte(fn, '.'); dot >= 0 {
+			fn = fn[dot+1:]
+		}
+		return fn
+	})
+}
+
+func (f *callerFilter) Match(context *LogContext) bool {
+	s := f.part(context)
+	if f.elems > 0 {
+		s = lastElems(s, f.elems)
+	}
+	ok, _ := path.Match(f.pattern, s)
+	return ok
+}
+
+func (f *callerFilter) ShouldRuntimeCaller() bool {
+	return true
+}
+
+type messageFilter struct {
+	re *regexp.Regexp
+}
+
+// MessageFilter matches records whose message matches re.
+func MessageFilter(re *regexp.Regexp) Filter {
+	return messageFilter{re}
+}
+
+func (f messageFilter) Match(context *LogContext) bool {
+	return f.re.MatchString(formatMessage(*context))
+}
+
+func (f messageFilter) ShouldRuntimeCaller() bool {
+	return false
+}
+
+// filterList is a list of filters combined by AndFilter or OrFilter.
+type filterList struct {
+	filters []Filter
+	and     bool
+}
+
+// AndFilter matches records that match all of the filters.
+func AndFilter(filters ...Filter) Filter {
+	return filterList{filters, true}
+}
+
+// OrFilter matches records that match any of the filters.
+func OrFilter(filters ...Filter) Filter {
+	return filterList{filters, false}
+}
+
+func (f filterList) Match(context *LogContext) bool {
+	for _, filter := range f.filters {
+		if filter.Match(context) != f.and {
+			return !f.and
+		}
+	}
+	return f.and
+}
+
+func (f filterList) ShouldRuntimeCaller() bool {
+	for _, filter := range f.filters {
+		if filter.ShouldRuntimeCaller() {
+			return true
+		}
+	}
+	return false
+}
+
+type notFilter struct {
+	filter Filter
+}
+
+// NotFilter matches records that don't match filter.
+func NotFilter(filter Filter) Filter {
+	return notFilter{filter}
+}
+
+func (f notFilter) Match(context *LogContext) bool {
+	return !f.filter.Match(context)
+}
+
+func (f notFilter) ShouldRuntimeCaller() bool {
+	return f.filter.ShouldRuntimeCaller()
+}
+
+// Include adds include filters to the standard logger.
+// See FactorLog.Include().
+func Include(filters ...Filter) {
+	std.Include(filters...)
+}
+
+// Exclude adds exclude filters to the standard logger.
+// See FactorLog.Exclude().
+func Exclude(filters ...Filter) {
+	std.Exclude(filters...)
+}
+
+// ClearFilters removes the filters of the standard logger.
+func ClearFilters() {
+	std.ClearFilters()
+}
@@ -0,0 +1,65 @@
+package factorlog
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+// mustFilter returns f, and panics if err isn't nil.
+func mustFilter(f Filter, err error) Filter {
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func TestFilters(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(buf, NewStdFormatter("%{SEVERITY} %{Message}"))
+	log.Include(mustFilter(PackageFilter("kdar/factorlog")), mustFilter(PackageFilter("other")))
+	log.Exclude(
+		AndFilter(mustFilter(FunctionFilter("TestFil*")), SeverityFilter(DEBUG|TRACE)),
+		MessageFilter(regexp.MustCompile(`^health`)),
+	)
+
+	log.Debug("debug")
+	log.Info("healthcheck")
+	log.Info("info")
+	log.Error("error")
+	if buf.String() != "INFO info\nERROR error\n" {
+		t.Fatalf("expected the filtered records, got %#v", buf.String())
+	}
+
+	buf.Reset()
+	log.ClearFilters()
+	log.Include(NotFilter(OrFilter(mustFilter(FileFilter("filter_test.go")), SeverityFilter(INFO))))
+	log.Error("error")
+	if buf.Len() != 0 {
+		t.Fatalf("expected the record to be filtered by file, got %#v", buf.String())
+	}
+}
+
+func TestFiltersCaller(t *testing.T) {
+	var caller []string
+	log := New(&bytes.Buffer{}, NewStdFormatter("%{Message}"))
+	log.AddHook(INFO, func(ctx *LogContext) bool {
+		caller = append(caller, ctx.File)
+		return true
+	})
+
+	// The caller is only looked up once a filter needs it.
+	log.Exclude(MessageFilter(regexp.MustCompile("x")))
+	log.Info("a")
+	log.Exclude(mustFilter(PackageFilter("nothing")))
+	log.Info("b")
+	if len(caller) != 2 || caller[0] != "" || caller[1] == "" {
+		t.Fatalf("expected the caller only with a caller filter, got %#v", caller)
+	}
+}
+
+func TestFilterBadPattern(t *testing.T) {
+	if _, err := PackageFilter("["); err == nil {
+		t.Fatal("expected a malformed pattern to return an error")
+	}
+}
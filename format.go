@@ -1,6 +1,8 @@
 package factorlog
 
 import (
+	"fmt"
+	"sync"
 	"time"
 )
 
@@ -132,11 +134,21 @@ func SeverityToIndex(sev Severity) int {
 	return count
 }
 
+// severityName returns the name of sev in table, or in def if table
+// is too short, e.g. nil in the zero value of a formatter.
+func severityName(table []string, def []string, sev Severity) string {
+	if i := SeverityToIndex(sev); i < len(table) {
+		return table[i]
+	}
+	return def[SeverityToIndex(sev)]
+}
+
 // Interface to format anything
 type Formatter interface {
 	// Formats LogRecord and returns the []byte that will
 	// be written by the log. This is not inherently thread
-	// safe but FactorLog uses a mutex before calling this.
+	// safe but FactorLog uses a mutex before calling this,
+	// unless the formatter is a ConcurrentFormatter.
 	Format(context LogContext) []byte
 
 	// Returns true if we should call runtime.Caller because
@@ -145,6 +157,31 @@ type Formatter interface {
 	ShouldRuntimeCaller() bool
 }
 
+// ConcurrentFormatter is implemented by formatters whose Format
+// can be called from several goroutines at once. FactorLog formats
+// records for these without holding a lock, so only the write
+// to the output is serialized.
+type ConcurrentFormatter interface {
+	Formatter
+	ConcurrentSafe() bool
+}
+
+func isConcurrentFormatter(f Formatter) bool {
+	cf, ok := f.(ConcurrentFormatter)
+	return ok && cf.ConcurrentSafe()
+}
+
+// AppendFormatter is implemented by formatters that can append a
+// record to an existing buffer. FactorLog prefers AppendFormat over
+// Format, passing it a pooled buffer, so formatting a record doesn't
+// allocate a new one each time.
+type AppendFormatter interface {
+	Formatter
+	// AppendFormat appends the formatted record to dst and returns
+	// the extended buffer, like the append built-in.
+	AppendFormat(dst []byte, context LogContext) []byte
+}
+
 // Structure used to hold the data used for formatting
 type LogContext struct {
 	Time     time.Time
@@ -152,9 +189,75 @@ type LogContext struct {
 	File     string
 	Line     int
 	Function string
+	PC       uintptr // program counter of the caller, set with File and Line
 	Pid      int
 	Format   *string
 	Args     []interface{}
+	Fields   []Field
+	Name     string
+}
+
+// Field is a key/value pair attached to a log record.
+// See FactorLog.With().
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// formatMessage returns the message of the context, formatted
+// with fmt.Sprintf if there is a format or fmt.Sprint if not.
+func formatMessage(context LogContext) string {
+	if context.Format != nil {
+		return fmt.Sprintf(*context.Format, context.Args...)
+	}
+	return fmt.Sprint(context.Args...)
+}
+
+// appendMessage appends the message of the context to dst. A single
+// string argument, the most common case, is appended as is without
+// going through fmt.
+func appendMessage(dst []byte, context LogContext) []byte {
+	if context.Format != nil {
+		return fmt.Appendf(dst, *context.Format, context.Args...)
+	}
+	if len(context.Args) == 1 {
+		if s, ok := context.Args[0].(string); ok {
+			return append(dst, s...)
+		}
+	}
+	return fmt.Append(dst, context.Args...)
+}
+
+// Returned as the value of a key that was passed to With()
+// without a value.
+const missingFieldValue = "(MISSING)"
+
+// makeFields converts a list of alternating keys and values
+// into fields. Any Field passed is appended as is. Keys that
+// aren't strings are converted with fmt.Sprint.
+func makeFields(keyvals []interface{}) []Field {
+	fields := make([]Field, 0, (len(keyvals)+1)/2)
+	for i := 0; i < len(keyvals); i++ {
+		if f, ok := keyvals[i].(Field); ok {
+			fields = append(fields, f)
+			continue
+		}
+
+		key, ok := keyvals[i].(string)
+		if !ok {
+			key = fmt.Sprint(keyvals[i])
+		}
+
+		var value interface{} = missingFieldValue
+		if i+1 < len(keyvals) {
+			i++
+			value = keyvals[i]
+		}
+
+		fields = append(fields, Field{key, value})
+	}
+
+	return fields
 }
 
 // // GetStack returns a stack trace from the runtime
@@ -176,6 +279,50 @@ type LogContext struct {
 // 	return trace
 // }
 
+// scratchPool holds temporary buffers for formatters to use with
+// the digit functions below, so they don't have to keep one per
+// formatter and serialize access to it.
+var scratchPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 64)
+		return &b
+	},
+}
+
+// getScratch returns a 64 byte temporary buffer from the pool.
+func getScratch() *[]byte {
+	return scratchPool.Get().(*[]byte)
+}
+
+func putScratch(b *[]byte) {
+	scratchPool.Put(b)
+}
+
+// Buffers larger than this aren't returned to bufferPool, so one
+// huge record doesn't keep its memory around forever.
+const maxPooledBuffer = 64 << 10
+
+// bufferPool holds buffers that records are formatted into.
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 256)
+		return &b
+	},
+}
+
+// getBuffer returns an empty buffer from the pool.
+func getBuffer() *[]byte {
+	return bufferPool.Get().(*[]byte)
+}
+
+func putBuffer(b *[]byte) {
+	if cap(*b) > maxPooledBuffer {
+		return
+	}
+	*b = (*b)[:0]
+	bufferPool.Put(b)
+}
+
 const digits = "0123456789"
 
 // twoDigits converts an integer d to its ascii representation
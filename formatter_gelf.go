@@ -0,0 +1,119 @@
+package factorlog
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// GELFFormatter formats each record as a GELF 1.1 message, the JSON
+// format read by Graylog. The severity is mapped to a syslog level with
+// SyslogSeverities, the caller is written as _file, _line and _function,
+// and fields added with With() are written as additional fields, with
+// a leading underscore. Use it with NewGELFWriter() to send records
+// to a Graylog server.
+// Example output:
+//   {"version":"1.1","host":"myhost","short_message":"hello there!","timestamp":1389223634.123456,"level":0,"_pid":1234,"_file":"path/to/testing.go","_line":391,"_function":"some crazy/path.path/pkg.(*Type).Function"}
+type GELFFormatter struct {
+	// Host is the name of the host sending the message.
+	Host string
+
+	// Caller includes the file, line and function of the caller.
+	Caller bool
+}
+
+// NewGELFFormatter returns a GELFFormatter with the hostname of this
+// machine and the caller included.
+func NewGELFFormatter() *GELFFormatter {
+	host, _ := os.Hostname()
+	return &GELFFormatter{
+		Host:   host,
+		Caller: true,
+	}
+}
+
+func (f *GELFFormatter) ShouldRuntimeCaller() bool {
+	return f.Caller
+}
+
+// ConcurrentSafe returns true. See ConcurrentFormatter.
+func (f *GELFFormatter) ConcurrentSafe() bool {
+	return true
+}
+
+func (f *GELFFormatter) Format(context LogContext) []byte {
+	return f.AppendFormat(make([]byte, 0, 256), context)
+}
+
+// AppendFormat is like Format but appends the record to dst and
+// returns the extended buffer. See AppendFormatter.
+func (f *GELFFormatter) AppendFormat(dst []byte, context LogContext) []byte {
+	buf := append(dst, `{"version":"1.1","host":`...)
+	buf = appendJSONString(buf, f.Host)
+
+	// The short message is the first line; the whole message is
+	// only repeated as the full message if it has more.
+	message := formatMessage(context)
+	short := message
+	if i := strings.IndexByte(message, '\n'); i >= 0 {
+		short = message[:i]
+	}
+	buf = append(buf, `,"short_message":`...)
+	buf = appendJSONString(buf, short)
+	if short != message {
+		buf = append(buf, `,"full_message":`...)
+		buf = appendJSONString(buf, message)
+	}
+
+	nsec := context.Time.UnixNano()
+	buf = append(buf, `,"timestamp":`...)
+	buf = strconv.AppendInt(buf, nsec/1e9, 10)
+	buf = append(buf, '.')
+	usec := nsec % 1e9 / 1e3
+	for d := int64(1e5); d > 1 && usec < d; d /= 10 {
+		buf = append(buf, '0')
+	}
+	buf = strconv.AppendInt(buf, usec, 10)
+
+	buf = append(buf, `,"level":`...)
+	buf = strconv.AppendInt(buf, int64(SyslogSeverities[SeverityToIndex(context.Severity)]), 10)
+	buf = append(buf, `,"_pid":`...)
+	buf = strconv.AppendInt(buf, int64(context.Pid), 10)
+
+	if f.Caller {
+		buf = append(buf, `,"_file":`...)
+		buf = appendJSONString(buf, context.File)
+		buf = append(buf, `,"_line":`...)
+		buf = strconv.AppendInt(buf, int64(context.Line), 10)
+		buf = append(buf, `,"_function":`...)
+		buf = appendJSONString(buf, context.Function)
+	}
+
+	for _, field := range context.Fields {
+		buf = append(buf, `,"_`...)
+		buf = appendGELFFieldName(buf, field.Key)
+		buf = append(buf, `":`...)
+		buf = appendJSONValue(buf, field.Value)
+	}
+
+	return append(buf, '}', '\n')
+}
+
+// appendGELFFieldName appends the name of an additional field, which
+// may only hold letters, digits, underscores, dashes and dots. Other
+// characters are replaced with underscores. GELF reserves _id, so a
+// field named id is written as __id.
+func appendGELFFieldName(dst []byte, key string) []byte {
+	if key == "id" {
+		return append(dst, "_id"...)
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '-' || c == '.' {
+			dst = append(dst, c)
+		} else {
+			dst = append(dst, '_')
+		}
+	}
+	return dst
+}
@@ -0,0 +1,35 @@
+package factorlog
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestGELFFormatter(t *testing.T) {
+	f := NewGELFFormatter()
+	f.Host = "myhost"
+	out := string(f.Format(fmtTestsContext))
+	expect := `{"version":"1.1","host":"myhost","short_message":"hello there!","timestamp":1389223634.123456,"level":2,"_pid":1234,"_file":"path/to/testing.go","_line":391,"_function":"some crazy/path.path/pkg.(*Type).Function"}` + "\n"
+	if out != expect {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", expect, out)
+	}
+
+	f.Caller = false
+	context := LogContext{
+		Time:     time.Unix(5, 7000),
+		Severity: WARN,
+		Args:     []interface{}{"first line\nsecond line"},
+		Fields:   []Field{{"id", 1}, {"user name", "bob"}},
+	}
+	out = string(f.Format(context))
+	expect = `{"version":"1.1","host":"myhost","short_message":"first line","full_message":"first line\nsecond line","timestamp":5.000007,"level":4,"_pid":0,"__id":1,"_user_name":"bob"}` + "\n"
+	if out != expect {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", expect, out)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, out)
+	}
+}
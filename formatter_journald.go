@@ -0,0 +1,120 @@
+package factorlog
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// JournaldFormatter formats records as entries of the native journald
+// protocol: MESSAGE, PRIORITY (mapped with SyslogSeverities),
+// SYSLOG_IDENTIFIER and, with Caller set, CODE_FILE, CODE_LINE and
+// CODE_FUNC. Fields added with With() are written as extra journal
+// fields, with their names uppercased and any character journald
+// doesn't allow replaced with an underscore. Use it with
+// NewJournaldWriter(), or create both at once with NewJournaldSink().
+type JournaldFormatter struct {
+	// SyslogIdentifier is the name journalctl shows for the entry.
+	SyslogIdentifier string
+
+	// Caller includes the file, line and function of the caller.
+	Caller bool
+}
+
+// NewJournaldFormatter returns a JournaldFormatter with the program
+// name as identifier and the caller included.
+func NewJournaldFormatter() *JournaldFormatter {
+	return &JournaldFormatter{
+		SyslogIdentifier: filepath.Base(os.Args[0]),
+		Caller:           true,
+	}
+}
+
+func (f *JournaldFormatter) ShouldRuntimeCaller() bool {
+	return f.Caller
+}
+
+// ConcurrentSafe returns true. See ConcurrentFormatter.
+func (f *JournaldFormatter) ConcurrentSafe() bool {
+	return true
+}
+
+func (f *JournaldFormatter) Format(context LogContext) []byte {
+	return f.AppendFormat(make([]byte, 0, 256), context)
+}
+
+// AppendFormat is like Format but appends the entry to dst and
+// returns the extended buffer. See AppendFormatter.
+func (f *JournaldFormatter) AppendFormat(dst []byte, context LogContext) []byte {
+	buf := appendJournaldField(dst, "MESSAGE", formatMessage(context))
+	buf = append(buf, "PRIORITY="...)
+	buf = strconv.AppendInt(buf, int64(SyslogSeverities[SeverityToIndex(context.Severity)]), 10)
+	buf = append(buf, '\n')
+
+	if f.SyslogIdentifier != "" {
+		buf = appendJournaldField(buf, "SYSLOG_IDENTIFIER", f.SyslogIdentifier)
+	}
+
+	if f.Caller {
+		buf = appendJournaldField(buf, "CODE_FILE", context.File)
+		buf = append(buf, "CODE_LINE="...)
+		buf = strconv.AppendInt(buf, int64(context.Line), 10)
+		buf = append(buf, '\n')
+		buf = appendJournaldField(buf, "CODE_FUNC", context.Function)
+	}
+
+	for _, field := range context.Fields {
+		value, ok := field.Value.(string)
+		if !ok {
+			value = fmt.Sprint(field.Value)
+		}
+		buf = appendJournaldField(buf, journaldFieldName(field.Key), value)
+	}
+
+	return buf
+}
+
+// appendJournaldField appends a field as NAME=value and a newline, or,
+// if the value holds a newline, as the name and a newline followed by
+// the length of the value as a little endian uint64, the value and
+// a newline.
+func appendJournaldField(dst []byte, name, value string) []byte {
+	dst = append(dst, name...)
+	if strings.IndexByte(value, '\n') < 0 {
+		dst = append(dst, '=')
+		dst = append(dst, value...)
+		return append(dst, '\n')
+	}
+
+	dst = append(dst, '\n')
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	dst = append(dst, size[:]...)
+	dst = append(dst, value...)
+	return append(dst, '\n')
+}
+
+// journaldFieldName turns key into a valid journal field name: at most
+// 64 uppercase letters, digits and underscores, starting with a letter.
+func journaldFieldName(key string) string {
+	name := []byte(strings.ToUpper(key))
+	for i, c := range name {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			name[i] = '_'
+		}
+	}
+
+	// Names starting with an underscore are reserved for fields
+	// journald adds itself.
+	s := strings.TrimLeft(string(name), "_")
+	if s == "" || s[0] <= '9' {
+		s = "F_" + s
+	}
+	if len(s) > 64 {
+		s = s[:64]
+	}
+	return s
+}
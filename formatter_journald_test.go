@@ -0,0 +1,28 @@
+package factorlog
+
+import (
+	"testing"
+)
+
+func TestJournaldFormatter(t *testing.T) {
+	f := NewJournaldFormatter()
+	f.SyslogIdentifier = "myapp"
+	out := string(f.Format(fmtTestsContext))
+	expect := "MESSAGE=hello there!\nPRIORITY=2\nSYSLOG_IDENTIFIER=myapp\nCODE_FILE=path/to/testing.go\nCODE_LINE=391\nCODE_FUNC=some crazy/path.path/pkg.(*Type).Function\n"
+	if out != expect {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", expect, out)
+	}
+
+	f.Caller = false
+	f.SyslogIdentifier = ""
+	context := LogContext{
+		Severity: INFO,
+		Args:     []interface{}{"two\nlines"},
+		Fields:   []Field{{"request.id", 5}, {"_private", "x"}, {"1st", "y"}},
+	}
+	out = string(f.Format(context))
+	expect = "MESSAGE\n\x09\x00\x00\x00\x00\x00\x00\x00two\nlines\nPRIORITY=6\nREQUEST_ID=5\nPRIVATE=x\nF_1ST=y\n"
+	if out != expect {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", expect, out)
+	}
+}
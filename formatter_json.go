@@ -0,0 +1,298 @@
+package factorlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const hex = "0123456789abcdef"
+
+// JSONFormatter formats each record as a JSON object on a single
+// line. Fields added with With() are written as extra keys, prefixed
+// with "fields." if they clash with a key of the formatter, like
+// "fields.time".
+// Example output:
+//   {"time":"2014-01-08T23:27:14.123456789Z","severity":"INFO","message":"hello there!","pid":1234}
+type JSONFormatter struct {
+	// The keys used for each part of the record. An empty key
+	// leaves that part out of the object.
+	TimeKey     string
+	SeverityKey string
+	MessageKey  string
+	PidKey      string
+	FileKey     string
+	LineKey     string
+	FunctionKey string
+
+	// TimeLayout is passed to time.Format.
+	TimeLayout string
+
+	// SeverityStrings is the table used to name severities
+	// (e.g. UcSeverityStrings[:], LcSeverityStrings[:] or
+	// CapSeverityStrings[:]). If nil, UcSeverityStrings is used.
+	SeverityStrings []string
+
+	// Caller includes the file, line and function of the caller.
+	// This requires a call to runtime.Caller, so it is off by default.
+	Caller bool
+}
+
+// NewJSONFormatter returns a JSONFormatter with the keys time, severity,
+// message, pid, file, line and function, RFC3339 times with nanoseconds
+// and uppercase severities. Change the fields of the returned formatter
+// before handing it to a logger to configure it.
+func NewJSONFormatter() *JSONFormatter {
+	return &JSONFormatter{
+		TimeKey:         "time",
+		SeverityKey:     "severity",
+		MessageKey:      "message",
+		PidKey:          "pid",
+		FileKey:         "file",
+		LineKey:         "line",
+		FunctionKey:     "function",
+		TimeLayout:      time.RFC3339Nano,
+		SeverityStrings: UcSeverityStrings[:],
+	}
+}
+
+func (f *JSONFormatter) ShouldRuntimeCaller() bool {
+	return f.Caller && (f.FileKey != "" || f.LineKey != "" || f.FunctionKey != "")
+}
+
+// ConcurrentSafe returns true. See ConcurrentFormatter.
+func (f *JSONFormatter) ConcurrentSafe() bool {
+	return true
+}
+
+func (f *JSONFormatter) Format(context LogContext) []byte {
+	return f.AppendFormat(make([]byte, 0, 256), context)
+}
+
+// AppendFormat is like Format but appends the record to dst and
+// returns the extended buffer. See AppendFormatter.
+func (f *JSONFormatter) AppendFormat(dst []byte, context LogContext) []byte {
+	buf := append(dst, '{')
+	first := true
+
+	if f.TimeKey != "" {
+		buf = appendJSONKey(buf, f.TimeKey, first)
+		buf = append(buf, '"')
+		buf = context.Time.AppendFormat(buf, f.TimeLayout)
+		buf = append(buf, '"')
+		first = false
+	}
+
+	if f.SeverityKey != "" {
+		buf = appendJSONKey(buf, f.SeverityKey, first)
+		buf = appendJSONString(buf, severityName(f.SeverityStrings, UcSeverityStrings[:], context.Severity))
+		first = false
+	}
+
+	if f.MessageKey != "" {
+		buf = appendJSONKey(buf, f.MessageKey, first)
+		buf = appendJSONString(buf, formatMessage(context))
+		first = false
+	}
+
+	if f.PidKey != "" {
+		buf = appendJSONKey(buf, f.PidKey, first)
+		buf = strconv.AppendInt(buf, int64(context.Pid), 10)
+		first = false
+	}
+
+	if f.Caller {
+		if f.FileKey != "" {
+			buf = appendJSONKey(buf, f.FileKey, first)
+			buf = appendJSONString(buf, context.File)
+			first = false
+		}
+
+		if f.LineKey != "" {
+			buf = appendJSONKey(buf, f.LineKey, first)
+			buf = strconv.AppendInt(buf, int64(context.Line), 10)
+			first = false
+		}
+
+		if f.FunctionKey != "" {
+			buf = appendJSONKey(buf, f.FunctionKey, first)
+			buf = appendJSONString(buf, context.Function)
+			first = false
+		}
+	}
+
+	for _, field := range context.Fields {
+		key := field.Key
+		if f.reserved(key) {
+			key = "fields." + key
+		}
+		buf = appendJSONKey(buf, key, first)
+		buf = appendJSONValue(buf, field.Value)
+		first = false
+	}
+
+	return append(buf, '}', '\n')
+}
+
+// reserved reports whether key is one of the keys the formatter writes
+// itself.
+func (f *JSONFormatter) reserved(key string) bool {
+	switch key {
+	case "":
+		return false
+	case f.TimeKey, f.SeverityKey, f.MessageKey, f.PidKey:
+		return true
+	case f.FileKey, f.LineKey, f.FunctionKey:
+		return f.Caller
+	}
+	return false
+}
+
+// appendJSONKey appends a quoted key and a colon to dst, preceded
+// by a comma unless this is the first key of the object.
+func appendJSONKey(dst []byte, key string, first bool) []byte {
+	if !first {
+		dst = append(dst, ',')
+	}
+	dst = appendJSONString(dst, key)
+	return append(dst, ':')
+}
+
+// appendJSONString appends s to dst as a quoted JSON string. Invalid
+// UTF-8 is replaced with U+FFFD, like encoding/json does.
+func appendJSONString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+
+			dst = append(dst, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				dst = append(dst, '\\', c)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+
+		// U+2028 and U+2029 are valid JSON but break JavaScript
+		// parsers, so escape them like encoding/json does.
+		if r == '\u2028' || r == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hex[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+
+		i += size
+	}
+
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
+
+// appendJSONValue appends v to dst as JSON. Common types are handled
+// directly; anything else goes through encoding/json, falling back to
+// a string from fmt.Sprint if it can't be marshaled.
+func appendJSONValue(dst []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(dst, "null"...)
+	case string:
+		return appendJSONString(dst, v)
+	case bool:
+		return strconv.AppendBool(dst, v)
+	case int:
+		return strconv.AppendInt(dst, int64(v), 10)
+	case int8:
+		return strconv.AppendInt(dst, int64(v), 10)
+	case int16:
+		return strconv.AppendInt(dst, int64(v), 10)
+	case int32:
+		return strconv.AppendInt(dst, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(dst, v, 10)
+	case uint:
+		return strconv.AppendUint(dst, uint64(v), 10)
+	case uint8:
+		return strconv.AppendUint(dst, uint64(v), 10)
+	case uint16:
+		return strconv.AppendUint(dst, uint64(v), 10)
+	case uint32:
+		return strconv.AppendUint(dst, uint64(v), 10)
+	case uint64:
+		return strconv.AppendUint(dst, v, 10)
+	case float32:
+		return appendJSONFloat(dst, float64(v), 32)
+	case float64:
+		return appendJSONFloat(dst, v, 64)
+	case json.Marshaler:
+		// checked before error and fmt.Stringer so types that
+		// know how to marshal themselves get to do so.
+	case error, fmt.Stringer:
+		return appendJSONMethod(dst, v)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return appendJSONString(dst, fmt.Sprint(v))
+	}
+	return append(dst, b...)
+}
+
+// appendJSONMethod appends the result of the Error or String method of
+// v to dst. Like fmt, it recovers if the method panics: a nil pointer
+// is written as null, and anything else as fmt.Sprint writes it.
+func appendJSONMethod(dst []byte, v interface{}) (b []byte) {
+	defer func() {
+		if recover() != nil {
+			if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+				b = append(dst, "null"...)
+			} else {
+				b = appendJSONString(dst, fmt.Sprint(v))
+			}
+		}
+	}()
+
+	if err, ok := v.(error); ok {
+		return appendJSONString(dst, err.Error())
+	}
+	return appendJSONString(dst, v.(fmt.Stringer).String())
+}
+
+// appendJSONFloat appends f to dst. JSON has no representation for
+// NaN or infinity, so those are written as strings.
+func appendJSONFloat(dst []byte, f float64, bits int) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return appendJSONString(dst, strconv.FormatFloat(f, 'g', -1, bits))
+	}
+	return strconv.AppendFloat(dst, f, 'g', -1, bits)
+}
@@ -0,0 +1,126 @@
+package factorlog
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestJSONFormatter(t *testing.T) {
+	f := NewJSONFormatter()
+	out := string(f.Format(fmtTestsContext))
+	expect := `{"time":"2014-01-08T23:27:14.123456789Z","severity":"PANIC","message":"hello there!","pid":1234}` + "\n"
+	if out != expect {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", expect, out)
+	}
+
+	f.Caller = true
+	f.PidKey = ""
+	f.TimeLayout = "2006-01-02"
+	f.SeverityKey = "level"
+	f.SeverityStrings = LcSeverityStrings[:]
+	out = string(f.Format(fmtTestsContext))
+	expect = `{"time":"2014-01-08","level":"panic","message":"hello there!","file":"path/to/testing.go","line":391,"function":"some crazy/path.path/pkg.(*Type).Function"}` + "\n"
+	if out != expect {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", expect, out)
+	}
+}
+
+func TestJSONFormatterZero(t *testing.T) {
+	f := &JSONFormatter{SeverityKey: "level"}
+	out := string(f.Format(fmtTestsContext))
+	if out != `{"level":"PANIC"}`+"\n" {
+		t.Fatalf("expected the zero value to use UcSeverityStrings, got %#v", out)
+	}
+}
+
+type jsonTestError struct {
+	msg string
+}
+
+func (e *jsonTestError) Error() string {
+	return e.msg
+}
+
+func TestJSONFormatterFields(t *testing.T) {
+	f := NewJSONFormatter()
+	f.TimeKey = ""
+	f.PidKey = ""
+	context := LogContext{
+		Severity: INFO,
+		Args:     []interface{}{"hey"},
+		Fields: []Field{
+			{"err", (*jsonTestError)(nil)},
+			{"message", "clash"},
+			{"time", 1},
+		},
+	}
+
+	out := string(f.Format(context))
+	expect := `{"severity":"INFO","message":"hey","err":null,"fields.message":"clash","time":1}` + "\n"
+	if out != expect {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", expect, out)
+	}
+}
+
+func TestJSONFormatterEscaping(t *testing.T) {
+	f := NewJSONFormatter()
+	f.TimeKey = ""
+	f.PidKey = ""
+
+	message := "quote\" backslash\\ newline\n tab\t bell\x07 bad\xff sep  ünïcode"
+	context := LogContext{
+		Severity: INFO,
+		Args:     []interface{}{message},
+		Fields: []Field{
+			{"err", errors.New("boom")},
+			{"n", 3},
+			{"nil", nil},
+			{"list", []int{1, 2}},
+		},
+	}
+
+	out := f.Format(context)
+	if out[len(out)-1] != '\n' || len(out) < 2 || out[len(out)-2] != '}' {
+		t.Fatalf("expected a single object terminated by a newline, got %#v", string(out))
+	}
+	for _, c := range out[:len(out)-1] {
+		if c == '\n' {
+			t.Fatalf("expected no newline inside the object, got %#v", string(out))
+		}
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, out)
+	}
+
+	expect := "quote\" backslash\\ newline\n tab\t bell\x07 bad� sep  ünïcode"
+	if decoded["message"] != expect {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", expect, decoded["message"])
+	}
+	if decoded["err"] != "boom" || decoded["n"] != float64(3) || decoded["nil"] != nil {
+		t.Fatalf("fields were not encoded correctly: %s", out)
+	}
+	if list, ok := decoded["list"].([]interface{}); !ok || len(list) != 2 {
+		t.Fatalf("fields were not encoded correctly: %s", out)
+	}
+}
+
+func TestJSONShouldRuntimeCaller(t *testing.T) {
+	f := NewJSONFormatter()
+	if f.ShouldRuntimeCaller() {
+		t.Fatalf("Formatter should not need to call runtime.Caller().")
+	}
+	f.Caller = true
+	if !f.ShouldRuntimeCaller() {
+		t.Fatalf("Formatter should need to call runtime.Caller().")
+	}
+}
+
+func BenchmarkJSONFormatter(b *testing.B) {
+	f := NewJSONFormatter()
+	for x := 0; x < b.N; x++ {
+		f.Format(fmtTestsContext)
+	}
+}
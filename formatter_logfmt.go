@@ -0,0 +1,245 @@
+package factorlog
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// LogfmtFormatter formats each record as a line of logfmt key=value
+// pairs. Values holding spaces, quotes, equal signs or control
+// characters are quoted and escaped. Fields added with With() are
+// written after the message.
+// Example output:
+//   time=2014-01-08T23:27:14.123Z level=info msg="hello there!" file=testing.go line=391
+type LogfmtFormatter struct {
+	// TimeLayout is passed to time.Format. If empty, an optimized
+	// RFC3339 format with milliseconds is used.
+	TimeLayout string
+
+	// SeverityStrings is the table used to name severities
+	// (e.g. LcSeverityStrings[:] or UcShortSeverityStrings[:]).
+	// If nil, LcSeverityStrings is used.
+	SeverityStrings []string
+
+	// Caller includes the file and line of the caller.
+	// This requires a call to runtime.Caller, so it is off by default.
+	Caller bool
+}
+
+// NewLogfmtFormatter returns a LogfmtFormatter using the optimized time
+// format and lowercase severities.
+func NewLogfmtFormatter() *LogfmtFormatter {
+	return &LogfmtFormatter{
+		SeverityStrings: LcSeverityStrings[:],
+	}
+}
+
+func (f *LogfmtFormatter) ShouldRuntimeCaller() bool {
+	return f.Caller
+}
+
+// ConcurrentSafe returns true. See ConcurrentFormatter.
+func (f *LogfmtFormatter) ConcurrentSafe() bool {
+	return true
+}
+
+func (f *LogfmtFormatter) Format(context LogContext) []byte {
+	return f.AppendFormat(make([]byte, 0, 256), context)
+}
+
+// AppendFormat is like Format but appends the record to dst and
+// returns the extended buffer. See AppendFormatter.
+func (f *LogfmtFormatter) AppendFormat(dst []byte, context LogContext) []byte {
+	tmp := getScratch()
+	defer putScratch(tmp)
+
+	buf := append(dst, "time="...)
+	if f.TimeLayout != "" {
+		buf = appendLogfmtValue(buf, context.Time.Format(f.TimeLayout))
+	} else {
+		buf = appendLogfmtTime(buf, tmp, context)
+	}
+
+	buf = append(buf, " level="...)
+	buf = appendLogfmtValue(buf, severityName(f.SeverityStrings, LcSeverityStrings[:], context.Severity))
+
+	buf = append(buf, " msg="...)
+	buf = appendLogfmtValue(buf, formatMessage(context))
+
+	if f.Caller {
+		file := context.File
+		for i := len(file) - 1; i >= 0; i-- {
+			if file[i] == pathSeparator {
+				file = file[i+1:]
+				break
+			}
+		}
+		if file == "" {
+			file = "???"
+		}
+
+		buf = append(buf, " file="...)
+		buf = appendLogfmtValue(buf, file)
+		buf = append(buf, " line="...)
+		n := Itoa(tmp, 0, context.Line)
+		buf = append(buf, (*tmp)[:n]...)
+	}
+
+	for _, field := range context.Fields {
+		buf = append(buf, ' ')
+		buf = appendLogfmtKey(buf, field.Key)
+		buf = append(buf, '=')
+		switch v := field.Value.(type) {
+		case string:
+			buf = appendLogfmtValue(buf, v)
+		case int:
+			if v >= 0 {
+				n := Itoa(tmp, 0, v)
+				buf = append(buf, (*tmp)[:n]...)
+			} else {
+				buf = appendLogfmtValue(buf, fmt.Sprint(v))
+			}
+		case bool:
+			if v {
+				buf = append(buf, "true"...)
+			} else {
+				buf = append(buf, "false"...)
+			}
+		case error:
+			buf = appendLogfmtValue(buf, v.Error())
+		case nil:
+			buf = append(buf, "nil"...)
+		default:
+			buf = appendLogfmtValue(buf, fmt.Sprint(v))
+		}
+	}
+
+	return append(buf, '\n')
+}
+
+// appendLogfmtTime appends the time of the context in the format
+// 2006-01-02T15:04:05.000Z07:00, using tmp as scratch space.
+func appendLogfmtTime(buf []byte, tmp *[]byte, context LogContext) []byte {
+	year, month, day := context.Time.Date()
+	hour, min, sec := context.Time.Clock()
+	NDigits(tmp, 4, 0, year)
+	(*tmp)[4] = '-'
+	TwoDigits(tmp, 5, int(month))
+	(*tmp)[7] = '-'
+	TwoDigits(tmp, 8, day)
+	(*tmp)[10] = 'T'
+	TwoDigits(tmp, 11, hour)
+	(*tmp)[13] = ':'
+	TwoDigits(tmp, 14, min)
+	(*tmp)[16] = ':'
+	TwoDigits(tmp, 17, sec)
+	(*tmp)[19] = '.'
+	NDigits(tmp, 3, 20, context.Time.Nanosecond()/1000000)
+	n := 23
+
+	_, offset := context.Time.Zone()
+	if offset == 0 {
+		(*tmp)[n] = 'Z'
+		n++
+	} else {
+		(*tmp)[n] = '+'
+		if offset < 0 {
+			(*tmp)[n] = '-'
+			offset = -offset
+		}
+		offset /= 60
+		TwoDigits(tmp, n+1, offset/60)
+		(*tmp)[n+3] = ':'
+		TwoDigits(tmp, n+4, offset%60)
+		n += 6
+	}
+
+	return append(buf, (*tmp)[:n]...)
+}
+
+// appendLogfmtKey appends key to dst, replacing any character that
+// isn't allowed in a logfmt key with an underscore.
+func appendLogfmtKey(dst []byte, key string) []byte {
+	if key == "" {
+		return append(dst, '_')
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c <= ' ' || c == '=' || c == '"' || c >= utf8.RuneSelf {
+			c = '_'
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
+
+// appendLogfmtValue appends s to dst, quoting it if it is empty or
+// holds spaces, quotes, equal signs, control characters or invalid
+// UTF-8.
+func appendLogfmtValue(dst []byte, s string) []byte {
+	if !logfmtNeedsQuotes(s) {
+		return append(dst, s...)
+	}
+
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				dst = append(dst, s[start:i]...)
+				dst = append(dst, "\ufffd"...)
+				start = i + size
+			}
+			i += size
+			continue
+		}
+
+		if c >= ' ' && c != '"' && c != '\\' && c != 0x7f {
+			i++
+			continue
+		}
+
+		dst = append(dst, s[start:i]...)
+		switch c {
+		case '"', '\\':
+			dst = append(dst, '\\', c)
+		case '\n':
+			dst = append(dst, '\\', 'n')
+		case '\r':
+			dst = append(dst, '\\', 'r')
+		case '\t':
+			dst = append(dst, '\\', 't')
+		default:
+			dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		}
+		i++
+		start = i
+	}
+
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
+
+func logfmtNeedsQuotes(s string) bool {
+	if s == "" {
+		return true
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				return true
+			}
+			i += size
+			continue
+		}
+		if c <= ' ' || c == '=' || c == '"' || c == '\\' || c == 0x7f {
+			return true
+		}
+		i++
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package factorlog
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var logfmtTests = []struct {
+	context LogContext
+	out     string
+}{
+	{
+		fmtTestsContext,
+		`time=2014-01-08T23:27:14.123Z level=panic msg="hello there!"` + "\n",
+	},
+	{
+		LogContext{Time: fmtTestsContext.Time, Severity: INFO, Args: []interface{}{"hey"}},
+		"time=2014-01-08T23:27:14.123Z level=info msg=hey\n",
+	},
+	{
+		LogContext{Time: fmtTestsContext.Time, Severity: INFO, Args: []interface{}{""}},
+		`time=2014-01-08T23:27:14.123Z level=info msg=""` + "\n",
+	},
+	{
+		LogContext{Time: fmtTestsContext.Time, Severity: INFO, Args: []interface{}{"say \"hi\"\\ a=b\n\tend\x08"}},
+		`time=2014-01-08T23:27:14.123Z level=info msg="say \"hi\"\\ a=b\n\tend\u0008"` + "\n",
+	},
+	{
+		LogContext{Time: fmtTestsContext.Time, Severity: INFO, Args: []interface{}{"bad\xffbyte"}},
+		"time=2014-01-08T23:27:14.123Z level=info msg=\"bad�byte\"\n",
+	},
+	{
+		LogContext{Time: fmtTestsContext.Time, Severity: INFO, Args: []interface{}{"ünïcode"}},
+		"time=2014-01-08T23:27:14.123Z level=info msg=ünïcode\n",
+	},
+	{
+		LogContext{
+			Time:     time.Unix(0, 1389223634123456789).In(time.FixedZone("", -(5*3600 + 30*60))),
+			Severity: WARN,
+			Args:     []interface{}{"hey"},
+			Fields: []Field{
+				{"request_id", "abc"},
+				{"n", 42},
+				{"neg", -1},
+				{"ok", true},
+				{"err", errors.New("no such file")},
+				{"bad key", nil},
+			},
+		},
+		`time=2014-01-08T17:57:14.123-05:30 level=warn msg=hey request_id=abc n=42 neg=-1 ok=true err="no such file" bad_key=nil` + "\n",
+	},
+}
+
+func TestLogfmtFormatter(t *testing.T) {
+	f := NewLogfmtFormatter()
+	for _, tt := range logfmtTests {
+		out := string(f.Format(tt.context))
+		if tt.out != out {
+			t.Fatalf("\nexpected: %#v\ngot:      %#v", tt.out, out)
+		}
+	}
+}
+
+func TestLogfmtFormatterOptions(t *testing.T) {
+	f := NewLogfmtFormatter()
+	f.Caller = true
+	f.TimeLayout = "2006-01-02 15:04"
+	f.SeverityStrings = UcShortSeverityStrings[:]
+	if !f.ShouldRuntimeCaller() {
+		t.Fatalf("Formatter should need to call runtime.Caller().")
+	}
+
+	out := string(f.Format(fmtTestsContext))
+	expect := `time="2014-01-08 23:27" level=PANC msg="hello there!" file=testing.go line=391` + "\n"
+	if expect != out {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", expect, out)
+	}
+}
+
+func TestLogfmtFormatterZero(t *testing.T) {
+	f := &LogfmtFormatter{}
+	out := string(f.Format(fmtTestsContext))
+	if !strings.Contains(out, " level=panic ") {
+		t.Fatalf("expected the zero value to use LcSeverityStrings, got %#v", out)
+	}
+
+	f.SeverityStrings = []string{"none"}
+	if out := string(f.Format(fmtTestsContext)); !strings.Contains(out, " level=panic ") {
+		t.Fatalf("expected a short table to fall back to LcSeverityStrings, got %#v", out)
+	}
+}
+
+func BenchmarkLogfmtFormatter(b *testing.B) {
+	f := NewLogfmtFormatter()
+	f.Caller = true
+	for x := 0; x < b.N; x++ {
+		f.Format(fmtTestsContext)
+	}
+}
@@ -1,7 +1,6 @@
 package factorlog
 
 import (
-	"bytes"
 	"fmt"
 	"regexp"
 
@@ -38,6 +37,9 @@ const (
 	vColor
 	vMessage
 	vSafeMessage
+	vFields
+	vField
+	vName
 )
 
 const (
@@ -87,6 +89,9 @@ var (
 		"Color":        vColor,
 		"Message":      vMessage,
 		"SafeMessage":  vSafeMessage,
+		"Fields":       vFields,
+		"Field":        vField,
+		"Name":         vName,
 	}
 	timeMap = map[string]int{
 		"15:04:05":           fTime_Default,
@@ -105,16 +110,15 @@ type part struct {
 }
 
 type StdFormatter struct {
+	// UTC converts the time of each record to UTC before formatting
+	// it, like log.LUTC.
+	UTC bool
+
 	// the original format
 	frmt string
 	// a slice depicting each part of the format
 	// we build the final []byte from this
 	parts []*part
-	// temporary buffer to help in formatting.
-	// initialized by newFormatter
-	tmp []byte
-	// temporary buffer used for safe messages.
-	stmp []byte
 	// flags represents all the verbs we used.
 	// this is useful in speeding things up like
 	// not calling runtime.Caller if we don't have
@@ -149,11 +153,12 @@ type StdFormatter struct {
 //   %{Message} - The message.
 //   %{SafeMessage} - Safe message. It will escape any character below ASCII 32. This helps prevent
 //                    attacks like using 0x08 to backspace log entries.
+//   %{Fields} - All fields added with With(), as space separated key=value pairs.
+//   %{Field "<key>"} - The value of a single field (e.g. %{Field "request_id"}).
+//   %{Name} - The name of the logger, for loggers created by Named() (e.g. db.pool).
 func NewStdFormatter(frmt string) *StdFormatter {
 	f := &StdFormatter{
 		frmt: frmt,
-		tmp:  make([]byte, 64),
-		stmp: make([]byte, 0, 64),
 	}
 
 	matches := formatRe.FindAllStringSubmatchIndex(frmt, -1)
@@ -219,6 +224,10 @@ func NewStdFormatter(frmt string) *StdFormatter {
 				} else {
 					f.appendDefault(v, args)
 				}
+			case vField:
+				if len(args) > 0 {
+					f.appendDefault(v, args)
+				}
 			default:
 				f.appendDefault(v, args)
 			}
@@ -247,6 +256,13 @@ func (f *StdFormatter) appendString(s string) {
 	}
 }
 
+// appendFormatter appends the parts of other to f.
+func (f *StdFormatter) appendFormatter(other *StdFormatter) {
+	f.frmt += other.frmt
+	f.parts = append(f.parts, other.parts...)
+	f.flags |= other.flags
+}
+
 func (f *StdFormatter) appendDefault(verb fmtVerb, args []string) {
 	f.flags |= int(verb)
 	f.parts = append(f.parts, &part{
@@ -255,89 +271,108 @@ func (f *StdFormatter) appendDefault(verb fmtVerb, args []string) {
 	})
 }
 
+// ConcurrentSafe returns true. StdFormatter uses a temporary buffer
+// per call, so it can format from several goroutines at once.
+func (f *StdFormatter) ConcurrentSafe() bool {
+	return true
+}
+
 func (f *StdFormatter) Format(context LogContext) []byte {
-	buf := &bytes.Buffer{}
+	return f.AppendFormat(make([]byte, 0, 128), context)
+}
+
+// AppendFormat is like Format but appends the record to dst and
+// returns the extended buffer. See AppendFormatter.
+func (f *StdFormatter) AppendFormat(dst []byte, context LogContext) []byte {
+	tmp := getScratch()
+	defer putScratch(tmp)
+
+	if f.UTC {
+		context.Time = context.Time.UTC()
+	}
+
+	buf := dst
 	for _, p := range f.parts {
 		switch p.verb {
 		case vSTRING:
-			buf.WriteString(p.value)
+			buf = append(buf, p.value...)
 		case vSEVERITY:
-			buf.WriteString(UcSeverityStrings[SeverityToIndex(context.Severity)])
+			buf = append(buf, UcSeverityStrings[SeverityToIndex(context.Severity)]...)
 		case vSeverity:
-			buf.WriteString(CapSeverityStrings[SeverityToIndex(context.Severity)])
+			buf = append(buf, CapSeverityStrings[SeverityToIndex(context.Severity)]...)
 		case vseverity:
-			buf.WriteString(LcSeverityStrings[SeverityToIndex(context.Severity)])
+			buf = append(buf, LcSeverityStrings[SeverityToIndex(context.Severity)]...)
 		case vSEV:
-			buf.WriteString(UcShortSeverityStrings[SeverityToIndex(context.Severity)])
+			buf = append(buf, UcShortSeverityStrings[SeverityToIndex(context.Severity)]...)
 		case vSev:
-			buf.WriteString(CapShortSeverityStrings[SeverityToIndex(context.Severity)])
+			buf = append(buf, CapShortSeverityStrings[SeverityToIndex(context.Severity)]...)
 		case vsev:
-			buf.WriteString(LcShortSeverityStrings[SeverityToIndex(context.Severity)])
+			buf = append(buf, LcShortSeverityStrings[SeverityToIndex(context.Severity)]...)
 		case vS:
-			buf.WriteString(UcShortestSeverityStrings[SeverityToIndex(context.Severity)])
+			buf = append(buf, UcShortestSeverityStrings[SeverityToIndex(context.Severity)]...)
 		case vs:
-			buf.WriteString(LcShortestSeverityStrings[SeverityToIndex(context.Severity)])
+			buf = append(buf, LcShortestSeverityStrings[SeverityToIndex(context.Severity)]...)
 		case vDate:
 			year, month, day := context.Time.Date()
-			NDigits(&f.tmp, 4, 0, year)
-			f.tmp[4] = '-'
-			TwoDigits(&f.tmp, 5, int(month))
-			f.tmp[7] = '-'
-			TwoDigits(&f.tmp, 8, day)
-			buf.Write(f.tmp[:10])
+			NDigits(tmp, 4, 0, year)
+			(*tmp)[4] = '-'
+			TwoDigits(tmp, 5, int(month))
+			(*tmp)[7] = '-'
+			TwoDigits(tmp, 8, day)
+			buf = append(buf, (*tmp)[:10]...)
 		case vTime:
 			// Some optimization cases.
 			switch {
 			case p.flags == fTime_LogDate:
 				year, month, day := context.Time.Date()
-				NDigits(&f.tmp, 4, 0, year)
-				f.tmp[4] = '/'
-				TwoDigits(&f.tmp, 5, int(month))
-				f.tmp[7] = '/'
-				TwoDigits(&f.tmp, 8, day)
-				buf.Write(f.tmp[:10])
+				NDigits(tmp, 4, 0, year)
+				(*tmp)[4] = '/'
+				TwoDigits(tmp, 5, int(month))
+				(*tmp)[7] = '/'
+				TwoDigits(tmp, 8, day)
+				buf = append(buf, (*tmp)[:10]...)
 			case p.flags&(fTime_StampMilli|fTime_StampMicro|fTime_StampNano) != 0:
 				hour, min, sec := context.Time.Clock()
-				TwoDigits(&f.tmp, 0, hour)
-				f.tmp[2] = ':'
-				TwoDigits(&f.tmp, 3, min)
-				f.tmp[5] = ':'
-				TwoDigits(&f.tmp, 6, sec)
-				f.tmp[8] = '.'
+				TwoDigits(tmp, 0, hour)
+				(*tmp)[2] = ':'
+				TwoDigits(tmp, 3, min)
+				(*tmp)[5] = ':'
+				TwoDigits(tmp, 6, sec)
+				(*tmp)[8] = '.'
 				// Depending on what kind of stamp we're dealing with, we have
 				// to output the correct precision.
 				if p.flags == fTime_StampMilli {
-					NDigits(&f.tmp, 3, 9, context.Time.Nanosecond()/1000000)
-					buf.Write(f.tmp[:12])
+					NDigits(tmp, 3, 9, context.Time.Nanosecond()/1000000)
+					buf = append(buf, (*tmp)[:12]...)
 				} else if p.flags == fTime_StampMicro {
-					NDigits(&f.tmp, 6, 9, context.Time.Nanosecond()/1000)
-					buf.Write(f.tmp[:15])
+					NDigits(tmp, 6, 9, context.Time.Nanosecond()/1000)
+					buf = append(buf, (*tmp)[:15]...)
 				} else if p.flags == fTime_StampNano {
-					NDigits(&f.tmp, 9, 9, context.Time.Nanosecond())
-					buf.Write(f.tmp[:18])
+					NDigits(tmp, 9, 9, context.Time.Nanosecond())
+					buf = append(buf, (*tmp)[:18]...)
 				}
 			case p.flags == fTime_Provided:
-				buf.WriteString(context.Time.Format(p.args[0]))
+				buf = context.Time.AppendFormat(buf, p.args[0])
 			default:
 				hour, min, sec := context.Time.Clock()
-				TwoDigits(&f.tmp, 0, hour)
-				f.tmp[2] = ':'
-				TwoDigits(&f.tmp, 3, min)
-				f.tmp[5] = ':'
-				TwoDigits(&f.tmp, 6, sec)
-				buf.Write(f.tmp[:8])
+				TwoDigits(tmp, 0, hour)
+				(*tmp)[2] = ':'
+				TwoDigits(tmp, 3, min)
+				(*tmp)[5] = ':'
+				TwoDigits(tmp, 6, sec)
+				buf = append(buf, (*tmp)[:8]...)
 			}
 		case vUnix:
-			n := I64toa(&f.tmp, 0, context.Time.Unix())
-			buf.Write(f.tmp[:n])
+			n := I64toa(tmp, 0, context.Time.Unix())
+			buf = append(buf, (*tmp)[:n]...)
 		case vUnixNano:
-			n := I64toa(&f.tmp, 0, context.Time.UnixNano())
-			buf.Write(f.tmp[:n])
+			n := I64toa(tmp, 0, context.Time.UnixNano())
+			buf = append(buf, (*tmp)[:n]...)
 		case vPid:
-			n := Itoa(&f.tmp, 0, context.Pid)
-			buf.Write(f.tmp[:n])
+			n := Itoa(tmp, 0, context.Pid)
+			buf = append(buf, (*tmp)[:n]...)
 		case vFullFile:
-			buf.WriteString(context.File)
+			buf = append(buf, context.File...)
 		case vFile, vShortFile:
 			file := context.File
 			if len(file) == 0 {
@@ -358,12 +393,12 @@ func (f *StdFormatter) Format(context LogContext) []byte {
 				file = file[:len(file)-3]
 			}
 
-			buf.WriteString(file)
+			buf = append(buf, file...)
 		case vLine:
-			n := Itoa(&f.tmp, 0, context.Line)
-			buf.Write(f.tmp[:n])
+			n := Itoa(tmp, 0, context.Line)
+			buf = append(buf, (*tmp)[:n]...)
 		case vFullFunction:
-			buf.WriteString(context.Function)
+			buf = append(buf, context.Function...)
 		case vPkgFunction:
 			fun := context.Function
 			slash := len(fun) - 1
@@ -376,7 +411,7 @@ func (f *StdFormatter) Format(context LogContext) []byte {
 				fun = fun[slash+1:]
 			}
 
-			buf.WriteString(fun)
+			buf = append(buf, fun...)
 		case vFunction:
 			fun := context.Function
 
@@ -391,54 +426,55 @@ func (f *StdFormatter) Format(context LogContext) []byte {
 			}
 
 			fun = fun[lastDot+1:]
-			buf.WriteString(fun)
+			buf = append(buf, fun...)
 		case vColor:
 			// We must have args when we get here because of
 			// the parser ensuring this. No need testing for it.
 			if Severity(p.flags) == context.Severity {
-				buf.WriteString(p.value)
+				buf = append(buf, p.value...)
 			}
 		case vMessage:
-			if context.Format != nil {
-				buf.WriteString(fmt.Sprintf(*context.Format, context.Args...))
-			} else {
-				buf.WriteString(fmt.Sprint(context.Args...))
-			}
+			buf = appendMessage(buf, context)
 		case vSafeMessage:
-			message := ""
-			if context.Format != nil {
-				message = fmt.Sprintf(*context.Format, context.Args...)
-			} else {
-				message = fmt.Sprint(context.Args...)
+			mp := getBuffer()
+			*mp = appendMessage((*mp)[:0], context)
+			for _, c := range *mp {
+				if c < 32 {
+					(*tmp)[0] = '\\'
+					(*tmp)[1] = 'x'
+					TwoDigits(tmp, 2, int(c))
+					buf = append(buf, (*tmp)[:4]...)
+				} else {
+					buf = append(buf, c)
+				}
 			}
-
-			f.stmp = f.stmp[:0]
-			l := len(message)
-			ca := cap(f.stmp)
-			if l > ca {
-				f.stmp = make([]byte, 0, l)
-			} else if ca > 8000 { // don't let memory usage get too big
-				f.stmp = f.stmp[0:0:l]
+			putBuffer(mp)
+		case vFields:
+			for i, field := range context.Fields {
+				if i > 0 {
+					buf = append(buf, ' ')
+				}
+				buf = append(buf, field.Key...)
+				buf = append(buf, '=')
+				buf = fmt.Append(buf, field.Value)
 			}
-
-			for _, c := range message {
-				if int(c) < 32 {
-					f.tmp[0] = '\\'
-					f.tmp[1] = 'x'
-					TwoDigits(&f.tmp, 2, int(c))
-					f.stmp = append(f.stmp, f.tmp[:4]...)
-				} else {
-					f.stmp = append(f.stmp, byte(c))
+		case vField:
+			// Search backwards so a field added by a child logger
+			// overrides one with the same key from its parent.
+			for i := len(context.Fields) - 1; i >= 0; i-- {
+				if context.Fields[i].Key == p.args[0] {
+					buf = fmt.Append(buf, context.Fields[i].Value)
+					break
 				}
 			}
-			buf.Write(f.stmp)
+		case vName:
+			buf = append(buf, context.Name...)
 		}
 	}
 
-	b := buf.Bytes()
-	if buf.Len() > 0 && b[len(b)-1] != '\n' {
-		b = append(b, '\n')
+	if len(buf) > len(dst) && buf[len(buf)-1] != '\n' {
+		buf = append(buf, '\n')
 	}
 
-	return b
+	return buf
 }
@@ -241,6 +241,26 @@ var std2FmtTests = []struct {
 		"%{SafeMessage}",
 		"hey\\x08\\x08\\x08there\n",
 	},
+	{
+		LogContext{Args: []interface{}{"hey"}, Fields: []Field{{"request_id", "abc"}, {"n", 3}}},
+		"%{Message} %{Fields}",
+		"hey request_id=abc n=3\n",
+	},
+	{
+		LogContext{Fields: []Field{{"request_id", "abc"}, {"n", 3}}},
+		`[%{Field "request_id"}]`,
+		"[abc]\n",
+	},
+	{
+		LogContext{Fields: []Field{{"n", 3}, {"n", 4}}},
+		`%{Field "n"}`,
+		"4\n",
+	},
+	{
+		LogContext{Fields: []Field{{"n", 3}}},
+		`[%{Field "missing"}]`,
+		"[]\n",
+	},
 }
 
 func TestStdFormatter(t *testing.T) {
@@ -253,6 +273,37 @@ func TestStdFormatter(t *testing.T) {
 	}
 }
 
+func TestStdAppendFormat(t *testing.T) {
+	prefix := []byte("prefix ")
+	for _, tt := range std2FmtTests {
+		f := NewStdFormatter(tt.frmt)
+		dst := append([]byte(nil), prefix...)
+		out := string(f.AppendFormat(dst, tt.context))
+		if string(prefix)+tt.out != out {
+			t.Fatalf("\nfor: %v\nexpected: %#v\ngot:      %#v", tt.frmt, string(prefix)+tt.out, out)
+		}
+	}
+}
+
+func TestStdAppendFormatAllocs(t *testing.T) {
+	f := NewStdFormatter(`[%{Date} %{Time}] [%{SEVERITY}:%{File}:%{Line}] %{Message}`)
+	buf := make([]byte, 0, 256)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = f.AppendFormat(buf[:0], fmtTestsContext)
+	})
+	if allocs != 0 {
+		t.Fatalf("expected AppendFormat to not allocate, got %v allocations", allocs)
+	}
+}
+
+func TestStdSafeMessageUnicode(t *testing.T) {
+	f := NewStdFormatter("%{SafeMessage}")
+	out := string(f.Format(LogContext{Args: []interface{}{"ünïcode\x07"}}))
+	if out != "ünïcode\\x07\n" {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", "ünïcode\\x07\n", out)
+	}
+}
+
 func TestStdShouldRuntimeCaller(t *testing.T) {
 	f := NewStdFormatter("[%{Date} %{Time}]")
 	if f.ShouldRuntimeCaller() {
@@ -285,3 +336,12 @@ func BenchmarkStdFormatter(b *testing.B) {
 	// fmt.Printf("%d,%d,%d,%d\n", m.HeapSys, m.HeapAlloc,
 	// 	m.HeapIdle, m.HeapReleased)
 }
+
+func BenchmarkStdFormatterAppend(b *testing.B) {
+	f := NewStdFormatter(`[%{Date} %{Time}] [%{SEVERITY}:%{File}:%{Line}] %{Message}`)
+	buf := make([]byte, 0, 256)
+	b.ReportAllocs()
+	for x := 0; x < b.N; x++ {
+		buf = f.AppendFormat(buf[:0], fmtTestsContext)
+	}
+}
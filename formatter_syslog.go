@@ -0,0 +1,242 @@
+package factorlog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// SyslogFacility is a syslog facility, combined with the severity of
+// a record to make its priority.
+type SyslogFacility int
+
+const (
+	SyslogKern SyslogFacility = iota << 3
+	SyslogUser
+	SyslogMail
+	SyslogDaemon
+	SyslogAuth
+	SyslogSyslog
+	SyslogLpr
+	SyslogNews
+	SyslogUucp
+	SyslogCron
+	SyslogAuthpriv
+	SyslogFtp
+	_ // unused
+	_ // unused
+	_ // unused
+	_ // unused
+	SyslogLocal0
+	SyslogLocal1
+	SyslogLocal2
+	SyslogLocal3
+	SyslogLocal4
+	SyslogLocal5
+	SyslogLocal6
+	SyslogLocal7
+)
+
+// SyslogSeverities maps each Severity, by SeverityToIndex(), to a
+// syslog severity: 0 emerg, 1 alert, 2 crit, 3 err, 4 warning,
+// 5 notice, 6 info, 7 debug. PANIC maps to crit rather than emerg,
+// which syslog daemons broadcast to every logged in user.
+var SyslogSeverities = [...]int{
+	6, // NONE
+	7, // TRACE
+	7, // DEBUG
+	6, // INFO
+	4, // WARN
+	3, // ERROR
+	2, // CRITICAL
+	3, // STACK
+	1, // FATAL
+	2, // PANIC
+}
+
+// SyslogPriority returns the syslog priority of a record with the
+// given facility and severity.
+func SyslogPriority(facility SyslogFacility, sev Severity) int {
+	return int(facility) | SyslogSeverities[SeverityToIndex(sev)]
+}
+
+// SyslogFormatter formats records as syslog messages, either as
+// RFC 5424 or, with RFC3164 set, in the legacy BSD format. Use it with
+// NewSyslogWriter() to send records to a syslog daemon.
+//
+// With RFC 5424, the caller and the fields added with With() are
+// written as structured data. With RFC 3164, which has no structured
+// data, the caller is written before the message.
+// Example output:
+//   <14>1 2014-01-08T23:27:14.123456Z myhost myapp 1234 - [factorlog@32473 file="main.go" line="12" func="main.main"] hello there!
+//   <14>Jan  8 23:27:14 myhost myapp[1234]: main.go:12: hello there!
+type SyslogFormatter struct {
+	Facility SyslogFacility
+
+	// RFC3164 writes the legacy BSD format instead of RFC 5424.
+	RFC3164 bool
+
+	// Hostname, AppName and MsgID fill in the header. An empty
+	// value is written as "-" in RFC 5424 and left out in RFC 3164.
+	Hostname string
+	AppName  string
+	MsgID    string
+
+	// StructuredDataID is the SD-ID of the structured data element
+	// holding the caller and fields in RFC 5424.
+	StructuredDataID string
+
+	// Caller includes the file, line and function of the caller.
+	Caller bool
+}
+
+// NewSyslogFormatter returns an RFC 5424 SyslogFormatter for the given
+// facility, with the caller included and the hostname and program name
+// of this process.
+func NewSyslogFormatter(facility SyslogFacility) *SyslogFormatter {
+	hostname, _ := os.Hostname()
+	return &SyslogFormatter{
+		Facility:         facility,
+		Hostname:         hostname,
+		AppName:          filepath.Base(os.Args[0]),
+		StructuredDataID: "factorlog@32473",
+		Caller:           true,
+	}
+}
+
+func (f *SyslogFormatter) ShouldRuntimeCaller() bool {
+	return f.Caller
+}
+
+// ConcurrentSafe returns true. See ConcurrentFormatter.
+func (f *SyslogFormatter) ConcurrentSafe() bool {
+	return true
+}
+
+func (f *SyslogFormatter) Format(context LogContext) []byte {
+	return f.AppendFormat(make([]byte, 0, 256), context)
+}
+
+// AppendFormat is like Format but appends the record to dst and
+// returns the extended buffer. See AppendFormatter.
+func (f *SyslogFormatter) AppendFormat(dst []byte, context LogContext) []byte {
+	buf := append(dst, '<')
+	buf = strconv.AppendInt(buf, int64(SyslogPriority(f.Facility, context.Severity)), 10)
+	buf = append(buf, '>')
+
+	if f.RFC3164 {
+		buf = context.Time.AppendFormat(buf, "Jan _2 15:04:05")
+		if f.Hostname != "" {
+			buf = append(buf, ' ')
+			buf = append(buf, f.Hostname...)
+		}
+		buf = append(buf, ' ')
+		buf = append(buf, f.AppName...)
+		buf = append(buf, '[')
+		buf = strconv.AppendInt(buf, int64(context.Pid), 10)
+		buf = append(buf, "]: "...)
+		if f.Caller {
+			buf = append(buf, filepath.Base(context.File)...)
+			buf = append(buf, ':')
+			buf = strconv.AppendInt(buf, int64(context.Line), 10)
+			buf = append(buf, ": "...)
+		}
+	} else {
+		buf = append(buf, "1 "...)
+		buf = context.Time.AppendFormat(buf, "2006-01-02T15:04:05.000000Z07:00")
+		buf = append(buf, ' ')
+		buf = appendSyslogHeader(buf, f.Hostname, 255)
+		buf = append(buf, ' ')
+		buf = appendSyslogHeader(buf, f.AppName, 48)
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, int64(context.Pid), 10)
+		buf = append(buf, ' ')
+		buf = appendSyslogHeader(buf, f.MsgID, 32)
+		buf = append(buf, ' ')
+		buf = f.appendStructuredData(buf, context)
+		buf = append(buf, ' ')
+	}
+
+	buf = appendMessage(buf, context)
+	return append(buf, '\n')
+}
+
+// appendStructuredData appends the caller and fields as a single
+// structured data element, or "-" if there are none.
+func (f *SyslogFormatter) appendStructuredData(dst []byte, context LogContext) []byte {
+	if (!f.Caller && len(context.Fields) == 0) || f.StructuredDataID == "" {
+		return append(dst, '-')
+	}
+
+	dst = append(dst, '[')
+	dst = appendSyslogName(dst, f.StructuredDataID)
+	if f.Caller {
+		dst = append(dst, ` file="`...)
+		dst = appendSyslogParamValue(dst, filepath.Base(context.File))
+		dst = append(dst, `" line="`...)
+		dst = strconv.AppendInt(dst, int64(context.Line), 10)
+		dst = append(dst, `" func="`...)
+		dst = appendSyslogParamValue(dst, context.Function)
+		dst = append(dst, '"')
+	}
+	for _, field := range context.Fields {
+		dst = append(dst, ' ')
+		dst = appendSyslogName(dst, field.Key)
+		dst = append(dst, '=', '"')
+		if s, ok := field.Value.(string); ok {
+			dst = appendSyslogParamValue(dst, s)
+		} else {
+			dst = appendSyslogParamValue(dst, fmt.Sprint(field.Value))
+		}
+		dst = append(dst, '"')
+	}
+	return append(dst, ']')
+}
+
+// appendSyslogHeader appends a header field of RFC 5424, which must be
+// printable ASCII without spaces. Other bytes are replaced with '_',
+// the value is cut at max bytes and an empty value is written as "-".
+func appendSyslogHeader(dst []byte, s string, max int) []byte {
+	if s == "" {
+		return append(dst, '-')
+	}
+	if len(s) > max {
+		s = s[:max]
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c > 32 && c < 127 {
+			dst = append(dst, c)
+		} else {
+			dst = append(dst, '_')
+		}
+	}
+	return dst
+}
+
+// appendSyslogName appends an SD-ID or PARAM-NAME, which is like a
+// header field but may not hold '=', ']' or '"' and is at most 32 bytes.
+func appendSyslogName(dst []byte, s string) []byte {
+	start := len(dst)
+	dst = appendSyslogHeader(dst, s, 32)
+	for i := start; i < len(dst); i++ {
+		if c := dst[i]; c == '=' || c == ']' || c == '"' {
+			dst[i] = '_'
+		}
+	}
+	return dst
+}
+
+// appendSyslogParamValue appends a PARAM-VALUE, escaping '"', '\'
+// and ']' with a backslash.
+func appendSyslogParamValue(dst []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\', ']':
+			dst = append(dst, '\\', c)
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
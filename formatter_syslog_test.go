@@ -0,0 +1,64 @@
+package factorlog
+
+import (
+	"testing"
+)
+
+func TestSyslogFormatter(t *testing.T) {
+	f := NewSyslogFormatter(SyslogLocal0)
+	f.Hostname = "myhost"
+	f.AppName = "myapp"
+	out := string(f.Format(fmtTestsContext))
+	expect := `<130>1 2014-01-08T23:27:14.123456Z myhost myapp 1234 - [factorlog@32473 file="testing.go" line="391" func="some crazy/path.path/pkg.(*Type).Function"] hello there!` + "\n"
+	if out != expect {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", expect, out)
+	}
+
+	f.Caller = false
+	f.Hostname = ""
+	f.MsgID = "my msg"
+	context := fmtTestsContext
+	context.Severity = WARN
+	context.Fields = []Field{{"k=1", `a "quoted" ]value\`}, {"n", 3}}
+	out = string(f.Format(context))
+	expect = `<132>1 2014-01-08T23:27:14.123456Z - myapp 1234 my_msg [factorlog@32473 k_1="a \"quoted\" \]value\\" n="3"] hello there!` + "\n"
+	if out != expect {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", expect, out)
+	}
+
+	context.Fields = nil
+	out = string(f.Format(context))
+	expect = `<132>1 2014-01-08T23:27:14.123456Z - myapp 1234 my_msg - hello there!` + "\n"
+	if out != expect {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", expect, out)
+	}
+}
+
+func TestSyslogFormatterRFC3164(t *testing.T) {
+	f := NewSyslogFormatter(SyslogUser)
+	f.RFC3164 = true
+	f.Hostname = "myhost"
+	f.AppName = "myapp"
+	out := string(f.Format(fmtTestsContext))
+	expect := "<10>Jan  8 23:27:14 myhost myapp[1234]: testing.go:391: hello there!\n"
+	if out != expect {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", expect, out)
+	}
+}
+
+func TestSyslogPriority(t *testing.T) {
+	for sev, expect := range map[Severity]int{
+		TRACE:    7,
+		DEBUG:    7,
+		INFO:     6,
+		WARN:     4,
+		ERROR:    3,
+		CRITICAL: 2,
+		FATAL:    1,
+		PANIC:    2,
+	} {
+		if p := SyslogPriority(SyslogDaemon, sev); p != 24+expect {
+			t.Fatalf("%s: expected priority %d, got %d", UcSeverityStrings[SeverityToIndex(sev)], 24+expect, p)
+		}
+	}
+}
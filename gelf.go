@@ -0,0 +1,200 @@
+package factorlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GELFCompression is the compression a GELFWriter applies to UDP messages.
+type GELFCompression int
+
+const (
+	GELFCompressGzip GELFCompression = iota
+	GELFCompressZlib
+	GELFCompressNone
+)
+
+const (
+	gelfChunkHeaderSize = 12
+	gelfMaxChunks       = 128
+	gelfChunkSize       = 1420 // default ChunkSize
+)
+
+// errGELFTooLarge is returned for a UDP message that needs more than
+// gelfMaxChunks chunks. Sending it again can't help.
+var errGELFTooLarge = errors.New("factorlog: GELF message too large to send over UDP")
+
+var gelfChunkMagic = []byte{0x1e, 0x0f}
+
+// GELFWriter is an io.Writer that sends each Write as one GELF message
+// to a Graylog server. Pair it with a GELFFormatter:
+//   w, err := factorlog.NewGELFWriter("udp", "graylog:12201")
+//   if err != nil {
+//     ...
+//   }
+//   log := factorlog.New(w, factorlog.NewGELFFormatter())
+// Over UDP, messages are compressed and split into chunks when they
+// don't fit in a single datagram. Over TCP, messages are sent
+// uncompressed and terminated by a null byte. If the connection
+// fails, the writer dials again and retries the write once. Set the
+// fields before the first Write.
+type GELFWriter struct {
+	// Compression applies to UDP only. It defaults to gzip.
+	Compression GELFCompression
+	// ChunkSize is the largest datagram sent over UDP, including the
+	// chunk header. It defaults to 1420, which fits most networks,
+	// and so does any size too small to hold the chunk header.
+	ChunkSize int
+
+	mu      sync.Mutex
+	network string
+	addr    string
+	conn    net.Conn
+	closed  bool
+	buf     bytes.Buffer
+}
+
+// NewGELFWriter connects to the Graylog server at addr over network,
+// which is "udp" or "tcp".
+func NewGELFWriter(network, addr string) (*GELFWriter, error) {
+	w := &GELFWriter{
+		ChunkSize: gelfChunkSize,
+		network:   network,
+		addr:      addr,
+	}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *GELFWriter) connect() error {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+
+	conn, err := net.DialTimeout(w.network, w.addr, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+// Write sends p as a single message.
+func (w *GELFWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	msg := bytes.TrimSuffix(p, []byte{'\n'})
+	w.buf.Reset()
+	if strings.HasPrefix(w.network, "tcp") {
+		w.buf.Write(msg)
+		w.buf.WriteByte(0)
+	} else if err := w.compress(msg); err != nil {
+		return 0, err
+	}
+
+	if w.conn != nil {
+		err := w.send(w.buf.Bytes())
+		if err == nil {
+			return len(p), nil
+		} else if err == errGELFTooLarge {
+			return 0, err
+		}
+	}
+
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+	if err := w.send(w.buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// compress writes msg to w.buf with the configured compression.
+func (w *GELFWriter) compress(msg []byte) error {
+	var zw io.WriteCloser
+	switch w.Compression {
+	case GELFCompressGzip:
+		zw = gzip.NewWriter(&w.buf)
+	case GELFCompressZlib:
+		zw = zlib.NewWriter(&w.buf)
+	default:
+		w.buf.Write(msg)
+		return nil
+	}
+
+	if _, err := zw.Write(msg); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// send writes msg to the connection, in chunks if it is a UDP message
+// larger than ChunkSize.
+func (w *GELFWriter) send(msg []byte) error {
+	chunkSize := w.ChunkSize
+	if chunkSize <= gelfChunkHeaderSize {
+		chunkSize = gelfChunkSize
+	}
+	if strings.HasPrefix(w.network, "tcp") || len(msg) <= chunkSize {
+		_, err := w.conn.Write(msg)
+		return err
+	}
+
+	size := chunkSize - gelfChunkHeaderSize
+	count := (len(msg) + size - 1) / size
+	if count > gelfMaxChunks {
+		return errGELFTooLarge
+	}
+
+	chunk := make([]byte, 0, chunkSize)
+	chunk = append(chunk, gelfChunkMagic...)
+	chunk = chunk[:gelfChunkHeaderSize-2]
+	if _, err := rand.Read(chunk[2:]); err != nil {
+		return err
+	}
+	chunk = append(chunk, 0, byte(count))
+
+	for i := 0; i < count; i++ {
+		end := (i + 1) * size
+		if end > len(msg) {
+			end = len(msg)
+		}
+		chunk = chunk[:gelfChunkHeaderSize]
+		chunk[gelfChunkHeaderSize-2] = byte(i)
+		chunk = append(chunk, msg[i*size:end]...)
+		if _, err := w.conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the connection to the server.
+func (w *GELFWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
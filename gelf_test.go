@@ -0,0 +1,155 @@
+package factorlog
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+)
+
+// readGELF reads one GELF message from conn, reassembling it if it
+// is chunked, and decompresses it.
+func readGELF(t *testing.T, conn net.PacketConn) map[string]interface{} {
+	buf := make([]byte, 64<<10)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var chunks [][]byte
+	var id []byte
+	for received := 0; chunks == nil || received < len(chunks); {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		packet := append([]byte(nil), buf[:n]...)
+
+		if !bytes.HasPrefix(packet, gelfChunkMagic) {
+			chunks = [][]byte{packet}
+			break
+		}
+
+		if chunks == nil {
+			id = packet[2:10]
+			chunks = make([][]byte, packet[11])
+		}
+		if !bytes.Equal(packet[2:10], id) {
+			t.Fatal("expected every chunk to have the same message id")
+		}
+		chunks[packet[10]] = packet[12:]
+		received++
+	}
+
+	msg := bytes.Join(chunks, nil)
+	var r io.Reader = bytes.NewReader(msg)
+	var err error
+	switch {
+	case bytes.HasPrefix(msg, []byte{0x1f, 0x8b}):
+		r, err = gzip.NewReader(r)
+	case msg[0] == 0x78:
+		r, err = zlib.NewReader(r)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestGELFWriterUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w, err := NewGELFWriter("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.ChunkSize = 100
+	log := New(w, NewGELFFormatter())
+
+	for _, compression := range []GELFCompression{GELFCompressGzip, GELFCompressZlib, GELFCompressNone} {
+		w.Compression = compression
+
+		log.Info("short")
+		if m := readGELF(t, conn); m["short_message"] != "short" || m["level"] != float64(6) {
+			t.Fatalf("unexpected message %v", m)
+		}
+
+		// Random data doesn't compress, so this needs several chunks.
+		rnd := rand.New(rand.NewSource(1))
+		long := make([]byte, 2000)
+		for i := range long {
+			long[i] = 'a' + byte(rnd.Intn(26))
+		}
+		log.With("k", "v").Error(string(long))
+		m := readGELF(t, conn)
+		if m["short_message"] != string(long) || m["_k"] != "v" || m["_line"] == nil {
+			t.Fatalf("unexpected message %v", m)
+		}
+	}
+
+	w.ChunkSize = gelfChunkHeaderSize + 1
+	w.Compression = GELFCompressNone
+	c := w.conn
+	if _, err := w.Write(make([]byte, gelfMaxChunks+1)); err != errGELFTooLarge || w.conn != c {
+		t.Fatalf("expected an error for a message needing too many chunks without reconnecting, got %v", err)
+	}
+
+	// A chunk size too small for the header falls back to the default.
+	w.ChunkSize = 0
+	log.Info("default")
+	if m := readGELF(t, conn); m["short_message"] != "default" {
+		t.Fatalf("unexpected message %v", m)
+	}
+}
+
+func TestGELFWriterTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	w, err := NewGELFWriter("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	log := New(w, NewGELFFormatter())
+	log.Info("one")
+	log.Info("two\nlines")
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	r := bufio.NewReader(conn)
+	for _, expect := range [][2]string{{"one", ""}, {"two", "two\nlines"}} {
+		frame, err := r.ReadBytes(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(frame[:len(frame)-1], &m); err != nil {
+			t.Fatalf("%q: %v", frame, err)
+		}
+		if full, _ := m["full_message"].(string); m["short_message"] != expect[0] || full != expect[1] {
+			t.Fatalf("unexpected message %v", m)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package factorlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Handler returns an http.Handler to inspect and change the standard
+// logger and the loggers created by Named() at runtime.
+//
+// GET lists every logger, or a single one given by the name query
+// parameter (an empty name is the standard logger). The response is
+// JSON if the request has format=json or accepts application/json,
+// and a plain text table otherwise.
+//
+// PUT (or POST) changes the logger given by name with the query
+// parameters verbosity, severities (e.g. INFO|ERROR) or min and max,
+// which map to SetVerbosity, SetSeverities and SetMinMaxSeverity.
+// The same settings can be sent as a JSON body instead:
+//   {"verbosity": 2, "severities": ["INFO", "ERROR"]}
+//   {"min": "INFO", "max": "PANIC"}
+// The response shows the logger after the change.
+//
+// Example:
+//   http.Handle("/debug/log", factorlog.Handler())
+//   curl -X PUT 'localhost:8080/debug/log?name=db.pool&verbosity=3'
+func Handler() http.Handler {
+	return handler{}
+}
+
+type handler struct{}
+
+// loggerStatus is how a logger is shown by the handler.
+type loggerStatus struct {
+	Name       string   `json:"name"`
+	Verbosity  Level    `json:"verbosity"`
+	Severities []string `json:"severities"`
+}
+
+// loggerUpdate holds the changes a PUT request asks for.
+type loggerUpdate struct {
+	Verbosity  *Level   `json:"verbosity"`
+	Severities []string `json:"severities"`
+	Min        string   `json:"min"`
+	Max        string   `json:"max"`
+}
+
+func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET", "HEAD":
+		h.get(w, r)
+	case "PUT", "POST":
+		h.put(w, r)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h handler) get(w http.ResponseWriter, r *http.Request) {
+	var loggers []*FactorLog
+	if _, ok := r.URL.Query()["name"]; ok {
+		l, ok := lookupNamed(r.URL.Query().Get("name"))
+		if !ok {
+			http.Error(w, "logger not found", http.StatusNotFound)
+			return
+		}
+		loggers = append(loggers, l)
+	} else {
+		loggers = Loggers()
+	}
+
+	h.write(w, r, loggers)
+}
+
+func (h handler) put(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	l, ok := lookupNamed(q.Get("name"))
+	if !ok {
+		http.Error(w, "logger not found", http.StatusNotFound)
+		return
+	}
+
+	var u loggerUpdate
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+			http.Error(w, "bad JSON body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		if v := q.Get("verbosity"); v != "" {
+			level, err := strconv.ParseInt(v, 10, 32)
+			if err != nil {
+				http.Error(w, "bad verbosity: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			u.Verbosity = new(Level)
+			*u.Verbosity = Level(level)
+		}
+		if v := q.Get("severities"); v != "" {
+			u.Severities = strings.FieldsFunc(v, func(r rune) bool {
+				return r == '|' || r == ','
+			})
+		}
+		u.Min = q.Get("min")
+		u.Max = q.Get("max")
+	}
+
+	if err := h.apply(l, u); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.write(w, r, []*FactorLog{l})
+}
+
+// apply validates every change before making any of them, so a bad
+// request leaves the logger untouched.
+func (h handler) apply(l *FactorLog, u loggerUpdate) error {
+	var sev Severity
+	for _, s := range u.Severities {
+		v, err := parseSeverity(s)
+		if err != nil {
+			return err
+		}
+		sev |= v
+	}
+
+	if (u.Min == "") != (u.Max == "") {
+		return fmt.Errorf("min and max must be given together")
+	}
+	var min, max Severity
+	if u.Min != "" {
+		var err error
+		if min, err = parseSeverity(u.Min); err != nil {
+			return err
+		}
+		if max, err = parseSeverity(u.Max); err != nil {
+			return err
+		}
+	}
+
+	if u.Verbosity == nil && u.Severities == nil && u.Min == "" {
+		return fmt.Errorf("nothing to change; give verbosity, severities or min and max")
+	}
+
+	if u.Verbosity != nil {
+		l.SetVerbosity(*u.Verbosity)
+	}
+	if u.Severities != nil {
+		l.SetSeverities(sev)
+	}
+	if u.Min != "" {
+		l.SetMinMaxSeverity(min, max)
+	}
+	return nil
+}
+
+func (h handler) write(w http.ResponseWriter, r *http.Request, loggers []*FactorLog) {
+	statuses := make([]loggerStatus, len(loggers))
+	for i, l := range loggers {
+		statuses[i] = loggerStatus{
+			Name:       l.Name(),
+			Verbosity:  l.Verbosity(),
+			Severities: severityNames(l.Severities()),
+		}
+	}
+
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(statuses)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVERBOSITY\tSEVERITIES")
+	for _, s := range statuses {
+		name := s.Name
+		if name == "" {
+			name = "(std)"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", name, s.Verbosity, strings.Join(s.Severities, "|"))
+	}
+	tw.Flush()
+}
+
+// parseSeverity converts a severity name in any case to a Severity.
+func parseSeverity(s string) (Severity, error) {
+	sev := StringToSeverity(strings.ToUpper(strings.TrimSpace(s)))
+	if sev == -1 {
+		return 0, fmt.Errorf("unknown severity %q", s)
+	}
+	return sev, nil
+}
+
+// severityNames returns the names of the severities in sev.
+func severityNames(sev Severity) []string {
+	names := []string{}
+	for i, name := range UcSeverityStrings {
+		if sev&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
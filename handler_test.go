@@ -0,0 +1,93 @@
+package factorlog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandlerList(t *testing.T) {
+	t.Cleanup(func() { forgetNamed("testhandler") })
+	Named("testhandler.list")
+	h := Handler()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if !strings.HasPrefix(w.Body.String(), "NAME") || !strings.Contains(w.Body.String(), "\ntesthandler.list ") {
+		t.Fatalf("expected a table listing the logger, got:\n%s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept", "application/json")
+	h.ServeHTTP(w, r)
+	var statuses []loggerStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &statuses); err != nil {
+		t.Fatalf("expected JSON, got %q: %v", w.Body.String(), err)
+	}
+	if len(statuses) < 2 || statuses[0].Name != "" {
+		t.Fatalf("expected the standard logger first, got %+v", statuses)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/?name=testhandler.missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestHandlerUpdate(t *testing.T) {
+	t.Cleanup(func() { forgetNamed("testhandler") })
+	l := Named("testhandler.update")
+	h := Handler()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("PUT", "/?name=testhandler.update&verbosity=3&severities=info|Error&format=json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if l.Verbosity() != 3 || l.Severities() != INFO|ERROR {
+		t.Fatalf("expected verbosity 3 and INFO|ERROR, got %d and %d", l.Verbosity(), l.Severities())
+	}
+	var statuses []loggerStatus
+	json.Unmarshal(w.Body.Bytes(), &statuses)
+	if len(statuses) != 1 || strings.Join(statuses[0].Severities, "|") != "INFO|ERROR" {
+		t.Fatalf("expected the updated logger in the response, got %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/?name=testhandler.update", strings.NewReader(`{"min": "WARN", "max": "PANIC"}`))
+	r.Header.Set("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || l.Severities() != WARN|ERROR|CRITICAL|STACK|FATAL|PANIC {
+		t.Fatalf("expected WARN to PANIC, got status %d and %d", w.Code, l.Severities())
+	}
+
+	// Bad requests change nothing.
+	for _, query := range []string{
+		"verbosity=x",
+		"verbosity=1&severities=bogus",
+		"min=INFO",
+		"",
+	} {
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("PUT", "/?name=testhandler.update&"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%q: expected status 400, got %d", query, w.Code)
+		}
+	}
+	if l.Verbosity() != 3 {
+		t.Fatalf("expected a bad request to leave the verbosity alone, got %d", l.Verbosity())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("DELETE", "/", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") == "" {
+		t.Fatalf("expected status 405 with an Allow header, got %d", w.Code)
+	}
+}
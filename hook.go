@@ -0,0 +1,139 @@
+package factorlog
+
+// Hook is called with each record a logger is about to write, before
+// it is formatted. It can change the record, e.g. add fields by
+// assigning a new slice to Fields, or send it somewhere else, like a
+// metric or a pager. Returning false drops the record; later hooks
+// aren't called. The caller's file, line and function are only set
+// if a sink's formatter needs them. Changing Severity doesn't change
+// which sinks the record goes to. A hook is called from the goroutine
+// that logs the record, so it must be fast and must not log to the
+// same logger.
+type Hook func(context *LogContext) bool
+
+// WriteHook is called after a record has been written to the sinks
+// of a logger, with the first error returned by a sink's writer, or
+// nil if every write succeeded. For an asynchronous logger, it is
+// called from the background goroutine once the record is written.
+// Like a Hook, it must not log to the same logger.
+type WriteHook func(context *LogContext, err error)
+
+// hookEntry is a hook and the severities it is called for.
+type hookEntry struct {
+	severities Severity
+	hook       Hook
+}
+
+// writeHookEntry is a write hook and the severities it is called for.
+type writeHookEntry struct {
+	severities Severity
+	hook       WriteHook
+}
+
+// hookSet holds the hooks of a logger. It is copied on write.
+type hookSet struct {
+	before []hookEntry
+	after  []writeHookEntry
+}
+
+// AddHook adds a hook that is called for records of the given
+// severities before they are formatted, after the hooks added
+// before it.
+// Example:
+//   log.AddHook(factorlog.ERROR|factorlog.CRITICAL, func(ctx *factorlog.LogContext) bool {
+//     errorCount.Inc()
+//     return true
+//   })
+func (l *FactorLog) AddHook(sev Severity, hook Hook) {
+	l.changeHooks(func(hs *hookSet) {
+		hs.before = append(hs.before[:len(hs.before):len(hs.before)], hookEntry{sev, hook})
+	})
+}
+
+// AddWriteHook adds a hook that is called for records of the given
+// severities after they have been written, e.g. to count the records
+// that couldn't be written.
+// Example:
+//   log.AddWriteHook(factorlog.CRITICAL, func(ctx *factorlog.LogContext, err error) {
+//     pager.Send(ctx, err)
+//   })
+func (l *FactorLog) AddWriteHook(sev Severity, hook WriteHook) {
+	l.changeHooks(func(hs *hookSet) {
+		hs.after = append(hs.after[:len(hs.after):len(hs.after)], writeHookEntry{sev, hook})
+	})
+}
+
+// ClearHooks removes the hooks and write hooks of this logger. A
+// named logger stops inheriting the hooks of its parent.
+func (l *FactorLog) ClearHooks() {
+	l.changeHooks(func(hs *hookSet) {
+		*hs = hookSet{}
+	})
+}
+
+// changeHooks stores a changed copy of the hooks of l.
+func (l *FactorLog) changeHooks(change func(hs *hookSet)) {
+	l = l.cfg()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	hs := &hookSet{}
+	if old := l.loadHooks(); old != nil {
+		*hs = *old
+	}
+	change(hs)
+	l.hooks.Store(hs)
+	l.setOwn(ownHooks)
+}
+
+func (l *FactorLog) loadHooks() *hookSet {
+	hs, _ := l.owner(ownHooks).hooks.Load().(*hookSet)
+	return hs
+}
+
+// runHooks calls the hooks of l for the record, and reports whether
+// they all let it through.
+func (l *FactorLog) runHooks(context *LogContext) bool {
+	hs := l.loadHooks()
+	if hs == nil {
+		return true
+	}
+	for _, h := range hs.before {
+		if context.Severity&h.severities != 0 && !h.hook(context) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeHooks returns the write hooks of l, or nil if it has none.
+func (l *FactorLog) writeHooks() []writeHookEntry {
+	if hs := l.loadHooks(); hs != nil {
+		return hs.after
+	}
+	return nil
+}
+
+// runWriteHooks calls the write hooks for a written record.
+func runWriteHooks(hooks []writeHookEntry, context LogContext, err error) {
+	for _, h := range hooks {
+		if context.Severity&h.severities != 0 {
+			h.hook(&context, err)
+		}
+	}
+}
+
+// AddHook adds a hook to the standard logger. See FactorLog.AddHook().
+func AddHook(sev Severity, hook Hook) {
+	std.AddHook(sev, hook)
+}
+
+// AddWriteHook adds a write hook to the standard logger.
+// See FactorLog.AddWriteHook().
+func AddWriteHook(sev Severity, hook WriteHook) {
+	std.AddWriteHook(sev, hook)
+}
+
+// ClearHooks removes the hooks of the standard logger.
+func ClearHooks() {
+	std.ClearHooks()
+}
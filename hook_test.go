@@ -0,0 +1,83 @@
+package factorlog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(buf, NewStdFormatter("%{SEVERITY} %{Message} %{Fields}"))
+
+	var errors []string
+	log.AddHook(ERROR|CRITICAL, func(ctx *LogContext) bool {
+		errors = append(errors, formatMessage(*ctx))
+		return true
+	})
+	log.AddHook(Severity(maxint32), func(ctx *LogContext) bool {
+		if formatMessage(*ctx) == "secret" {
+			return false
+		}
+		ctx.Fields = append(ctx.Fields[:len(ctx.Fields):len(ctx.Fields)], Field{"host", "a"})
+		return true
+	})
+
+	log.With("id", 1).Error("hey")
+	log.Info("secret")
+	log.Info("there")
+	expect := "ERROR hey id=1 host=a\nINFO there host=a\n"
+	if buf.String() != expect {
+		t.Fatalf("expected %#v, got %#v", expect, buf.String())
+	}
+	if len(errors) != 1 || errors[0] != "hey" {
+		t.Fatalf("expected the ERROR hook to see only the error, got %v", errors)
+	}
+
+	log.ClearHooks()
+	log.Info("secret")
+	if buf.String() != expect+"INFO secret \n" {
+		t.Fatalf("expected no hooks after ClearHooks, got %#v", buf.String())
+	}
+}
+
+func TestHooksNamed(t *testing.T) {
+	t.Cleanup(func() { forgetNamed("testhooks") })
+	parent := Named("testhooks")
+	child := Named("testhooks.child")
+	parent.SetOutput(&bytes.Buffer{})
+
+	calls := 0
+	parent.AddHook(INFO, func(ctx *LogContext) bool {
+		calls++
+		return true
+	})
+	child.Info("hey")
+	if calls != 1 {
+		t.Fatalf("expected the child to inherit the parent's hooks, got %d calls", calls)
+	}
+}
+
+func TestWriteHooks(t *testing.T) {
+	log := New(&failWriter{ok: 1}, NewStdFormatter("%{Message}"))
+	log.SetAsync(10, OverflowBlock)
+	defer log.Close()
+
+	var written []string
+	var errs []error
+	log.AddWriteHook(INFO|ERROR, func(ctx *LogContext, err error) {
+		written = append(written, formatMessage(*ctx))
+		errs = append(errs, err)
+	})
+	log.AddHook(ERROR, func(ctx *LogContext) bool {
+		return false
+	})
+
+	log.Info("ok")
+	log.Info("fails")
+	log.Warn("not hooked")
+	log.Error("dropped")
+	log.Flush()
+	if len(written) != 2 || written[0] != "ok" || errs[0] != nil || written[1] != "fails" || errs[1] != errDiskFull {
+		t.Fatalf("expected the write hooks to see written records and their errors, got %v and %v", written, errs)
+	}
+}
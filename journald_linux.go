@@ -0,0 +1,119 @@
+package factorlog
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// JournaldSocket is the socket journald reads native protocol entries from.
+const JournaldSocket = "/run/systemd/journal/socket"
+
+// JournaldWriter is an io.Writer that sends each Write as one entry to
+// journald over its native protocol. Pair it with a JournaldFormatter,
+// or use NewJournaldSink(). Entries too large for a datagram are
+// written to a temporary file in /dev/shm, whose descriptor is passed
+// to journald instead.
+type JournaldWriter struct {
+	mu   sync.Mutex
+	conn *net.UnixConn
+	addr *net.UnixAddr
+}
+
+// NewJournaldWriter connects to journald at path, or at JournaldSocket
+// if path is empty.
+func NewJournaldWriter(path string) (*JournaldWriter, error) {
+	if path == "" {
+		path = JournaldSocket
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	// The socket is left unconnected, so journald restarting
+	// doesn't break it.
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &JournaldWriter{
+		conn: conn,
+		addr: &net.UnixAddr{Name: path, Net: "unixgram"},
+	}, nil
+}
+
+// NewJournaldSink returns a sink sending records to journald at path,
+// or at JournaldSocket if path is empty, formatted by a
+// JournaldFormatter. Close the logger using it, or the writer of the
+// sink, when done.
+// Example:
+//   sink, err := factorlog.NewJournaldSink("")
+//   if err != nil {
+//     ...
+//   }
+//   log := factorlog.NewMulti(sink)
+func NewJournaldSink(path string) (*Sink, error) {
+	w, err := NewJournaldWriter(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewSink(w, NewJournaldFormatter()), nil
+}
+
+// Write sends p as a single entry.
+func (w *JournaldWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return 0, os.ErrClosed
+	}
+
+	_, _, err := w.conn.WriteMsgUnix(p, nil, w.addr)
+	if err == nil {
+		return len(p), nil
+	}
+	if !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS) {
+		return 0, err
+	}
+
+	if err := w.writeFile(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeFile writes p to an unlinked temporary file and passes its
+// descriptor to journald.
+func (w *JournaldWriter) writeFile(p []byte) error {
+	dir := "/dev/shm"
+	if _, err := os.Stat(dir); err != nil {
+		dir = os.TempDir()
+	}
+	f, err := os.CreateTemp(dir, "factorlog-journal-")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	os.Remove(f.Name())
+
+	if _, err := f.Write(p); err != nil {
+		return err
+	}
+
+	_, _, err = w.conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), w.addr)
+	return err
+}
+
+// Close closes the connection to journald.
+func (w *JournaldWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,79 @@
+package factorlog
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// readJournald reads one entry from conn, following a passed file
+// descriptor if there is one.
+func readJournald(t *testing.T, conn *net.UnixConn) []byte {
+	buf := make([]byte, 64<<10)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oobn == 0 {
+		return buf[:n]
+	}
+
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		t.Fatal(err)
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := os.NewFile(uintptr(fds[0]), "journal")
+	defer f.Close()
+	f.Seek(0, io.SeekStart)
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestJournaldSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sink, err := NewJournaldSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Writer().(*JournaldWriter).Close()
+	log := NewMulti(sink)
+
+	log.With("user", "bob").Warn("hello")
+	entry := string(readJournald(t, conn))
+	for _, field := range []string{"MESSAGE=hello\n", "PRIORITY=4\n", "CODE_FILE=", "CODE_LINE=", "CODE_FUNC=", "USER=bob\n"} {
+		if !strings.Contains(entry, field) {
+			t.Fatalf("expected %q in entry %q", field, entry)
+		}
+	}
+	if !strings.Contains(entry, "journald_linux_test.go") {
+		t.Fatalf("expected the caller in entry %q", entry)
+	}
+
+	// Too large for a datagram, so it's passed as a file.
+	large := strings.Repeat("x", 4<<20)
+	log.Info(large)
+	if entry := readJournald(t, conn); !bytes.HasPrefix(entry, []byte("MESSAGE="+large+"\n")) {
+		t.Fatalf("expected the large entry to arrive whole, got %d bytes", len(entry))
+	}
+}
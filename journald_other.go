@@ -0,0 +1,37 @@
+//go:build !linux
+// +build !linux
+
+package factorlog
+
+import (
+	"errors"
+)
+
+// JournaldSocket is the socket journald reads native protocol entries from.
+const JournaldSocket = "/run/systemd/journal/socket"
+
+var errJournaldUnsupported = errors.New("factorlog: journald is only supported on linux")
+
+// JournaldWriter sends entries to journald. It is only supported on
+// linux; elsewhere NewJournaldWriter returns an error.
+type JournaldWriter struct{}
+
+// NewJournaldWriter returns an error, since journald only runs on linux.
+func NewJournaldWriter(path string) (*JournaldWriter, error) {
+	return nil, errJournaldUnsupported
+}
+
+// NewJournaldSink returns an error, since journald only runs on linux.
+func NewJournaldSink(path string) (*Sink, error) {
+	return nil, errJournaldUnsupported
+}
+
+// Write returns an error.
+func (w *JournaldWriter) Write(p []byte) (int, error) {
+	return 0, errJournaldUnsupported
+}
+
+// Close does nothing.
+func (w *JournaldWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,158 @@
+package factorlog
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// exitHooks are the functions run by Fatal before it exits.
+var exitHooks struct {
+	sync.Mutex
+	hooks []func()
+}
+
+// exitFunc holds the func(int) Fatal calls to exit.
+var exitFunc atomic.Value
+
+// AddExitHook adds a function that Fatal (and its variants) runs after
+// writing its record and flushing its logger, but before exiting. Use
+// it to flush or close other loggers and writers. Hooks run in the
+// order they were added.
+// Example:
+//   factorlog.AddExitHook(func() { log.Close() })
+func AddExitHook(hook func()) {
+	exitHooks.Lock()
+	exitHooks.hooks = append(exitHooks.hooks, hook)
+	exitHooks.Unlock()
+}
+
+// SetExitFunc replaces the function Fatal calls to exit the program,
+// os.Exit by default. This lets tests check what Fatal logs without
+// the test binary exiting. If the function returns, so does Fatal.
+// A nil function restores os.Exit.
+// Example:
+//   factorlog.SetExitFunc(func(code int) { exited = code })
+//   defer factorlog.SetExitFunc(nil)
+func SetExitFunc(exit func(code int)) {
+	if exit == nil {
+		exit = os.Exit
+	}
+	exitFunc.Store(exit)
+}
+
+// exit flushes l, runs the exit hooks and exits with status 1.
+func (l *FactorLog) exit() {
+	l.Flush()
+
+	exitHooks.Lock()
+	hooks := append([]func(){}, exitHooks.hooks...)
+	exitHooks.Unlock()
+	for _, hook := range hooks {
+		hook()
+	}
+
+	exit, _ := exitFunc.Load().(func(int))
+	if exit == nil {
+		exit = os.Exit
+	}
+	exit(1)
+}
+
+// Flush writes the notice for records held back by SetDedup, waits
+// until every record queued by an asynchronous logger has been
+// written, then flushes the writers of the logger's sinks that have a
+// Flush method, like bufio.Writer. It returns the first error returned
+// by a writer.
+func (l *FactorLog) Flush() error {
+	l = l.cfg()
+	if d := l.loadDedup(); d != nil {
+		d.flush()
+	}
+	if q := l.loadAsync(); q != nil {
+		q.flush()
+	}
+
+	var err error
+	for _, s := range l.loadSinks() {
+		s.mu.Lock()
+		switch w := s.Writer().(type) {
+		case interface{ Flush() error }:
+			if ferr := w.Flush(); ferr != nil && err == nil {
+				err = ferr
+			}
+		case interface{ Flush() }:
+			w.Flush()
+		}
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// Close turns duplicate suppression and sampling off, writing what
+// they held back, writes any queued records, turns asynchronous mode
+// off, flushes the writers of the logger's sinks and closes the ones
+// that implement io.Closer, except os.Stdout and os.Stderr. A named
+// logger only closes what it doesn't inherit from its parent: if it
+// inherits its sinks or asynchronous mode, it flushes them instead.
+// It returns the first error returned by a writer.
+func (l *FactorLog) Close() error {
+	l = l.cfg()
+	if l.owns(ownDedup) {
+		l.SetDedup(0)
+	}
+	if l.owns(ownSampling) {
+		l.SetSampling(nil)
+	}
+	if l.owns(ownAsync) {
+		l.SetAsync(0, OverflowBlock)
+	}
+
+	err := l.Flush()
+	if !l.owns(ownSinks) {
+		return err
+	}
+
+	var closed []io.Closer
+	for _, s := range l.loadSinks() {
+		out := s.Writer()
+		c, ok := out.(io.Closer)
+		if !ok || out == io.Writer(os.Stdout) || out == io.Writer(os.Stderr) || containsCloser(closed, c) {
+			continue
+		}
+		closed = append(closed, c)
+
+		s.mu.Lock()
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// containsCloser reports whether c is in closers. Writers shared by
+// several sinks are only closed once.
+func containsCloser(closers []io.Closer, c io.Closer) bool {
+	if !reflect.TypeOf(c).Comparable() {
+		return false
+	}
+	for _, other := range closers {
+		if reflect.TypeOf(other) == reflect.TypeOf(c) && other == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Flush flushes the standard logger. See FactorLog.Flush().
+func Flush() error {
+	return std.Flush()
+}
+
+// Close closes the standard logger. See FactorLog.Close().
+func Close() error {
+	return std.Close()
+}
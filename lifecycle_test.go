@@ -0,0 +1,113 @@
+package factorlog
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"testing"
+)
+
+// closeWriter counts the calls to Close.
+type closeWriter struct {
+	bytes.Buffer
+	closed int
+}
+
+func (w *closeWriter) Close() error {
+	w.closed++
+	return nil
+}
+
+func TestFlushClose(t *testing.T) {
+	buf := &closeWriter{}
+	bw := bufio.NewWriter(buf)
+	shared := &closeWriter{}
+	log := NewMulti(
+		NewSink(bw, NewStdFormatter("%{Message}")),
+		NewSink(shared, NewStdFormatter("%{Message}")),
+		NewSink(shared, NewStdFormatter("%{Message}")),
+		NewSink(os.Stderr, NewStdFormatter("%{Message}")),
+	)
+	log.SetSeverities(ERROR)
+
+	log.Error("hey")
+	if buf.Len() != 0 {
+		t.Fatal("expected the record to be buffered")
+	}
+	if err := log.Flush(); err != nil || buf.String() != "hey\n" {
+		t.Fatalf("expected Flush to flush the bufio.Writer, got %v and %#v", err, buf.String())
+	}
+
+	log.Close()
+	if shared.closed != 1 {
+		t.Fatalf("expected a writer shared by two sinks to be closed once, got %d", shared.closed)
+	}
+}
+
+func TestCloseNamed(t *testing.T) {
+	t.Cleanup(func() { forgetNamed("testclosenamed") })
+	w := &closeWriter{}
+	parent := Named("testclosenamed")
+	child := Named("testclosenamed.child")
+	parent.SetOutput(w)
+
+	child.Close()
+	if w.closed != 0 {
+		t.Fatal("expected a child not to close the writers it inherits")
+	}
+	parent.Close()
+	if w.closed != 1 {
+		t.Fatal("expected the parent to close its writer")
+	}
+}
+
+func TestFatalExit(t *testing.T) {
+	var calls []string
+	SetExitFunc(func(code int) {
+		calls = append(calls, "exit")
+		if code != 1 {
+			t.Fatalf("expected exit code 1, got %d", code)
+		}
+	})
+	defer SetExitFunc(nil)
+	defer func() { exitHooks.hooks = nil }()
+	AddExitHook(func() { calls = append(calls, "hook 1") })
+	AddExitHook(func() { calls = append(calls, "hook 2") })
+
+	// An asynchronous, buffered logger still gets the record out
+	// before exiting.
+	buf := &bytes.Buffer{}
+	bw := bufio.NewWriter(buf)
+	log := New(bw, NewStdFormatter("%{SEVERITY} %{Message}"))
+	log.SetAsync(10, OverflowBlock)
+	defer log.Close()
+
+	log.Fatalf("fatal %d", 1)
+	if buf.String() != "FATAL fatal 1\n" {
+		t.Fatalf("expected the record to be written before exiting, got %#v", buf.String())
+	}
+	log.V(0).Fatal("fatal 2")
+	if len(calls) != 6 || calls[0] != "hook 1" || calls[1] != "hook 2" || calls[2] != "exit" {
+		t.Fatalf("expected the hooks to run in order before exiting, got %v", calls)
+	}
+}
+
+func TestPanicFlush(t *testing.T) {
+	buf := &bytes.Buffer{}
+	bw := bufio.NewWriter(buf)
+	log := New(bw, NewStdFormatter("%{SEVERITY} %{Message}"))
+	log.SetAsync(10, OverflowBlock)
+	defer log.Close()
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected Panicf to panic")
+			}
+		}()
+		log.Panicf("panic %d", 1)
+	}()
+	if buf.String() != "PANIC panic 1\n" {
+		t.Fatalf("expected the record to be written before panicking, got %#v", buf.String())
+	}
+}
@@ -0,0 +1,191 @@
+package factorlog
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Settings a named logger can override rather than inherit
+// from its parent. See FactorLog.own.
+const (
+	ownVerbosity int32 = 1 << iota
+	ownSeverities
+	ownSinks
+	ownAsync
+	ownVModule
+	ownErrors
+	ownPrint
+	ownSampling
+	ownDedup
+	ownHooks
+	ownFilters
+)
+
+// registry holds every logger created by Named().
+var registry = struct {
+	sync.Mutex
+	loggers map[string]*FactorLog
+}{loggers: make(map[string]*FactorLog)}
+
+// Named returns the logger with the given name from a process-wide
+// registry, creating it if needed. Names form a hierarchy separated
+// by dots: "db.pool" is a child of "db", and "db" is a child of the
+// standard logger, which is also returned for an empty name.
+// A named logger inherits its verbosity, severities, vmodule rules,
+// outputs, asynchronous queue, write error handling, print severity,
+// sampling, duplicate suppression, hooks and filters from its parent
+// until it sets its own, and picks up changes made to the parent at
+// runtime. Changing the outputs of a named logger (SetOutput,
+// SetFormatter, AddSink, RemoveSink) starts from a copy of the ones it
+// inherited.
+// Example:
+//   factorlog.Named("db").SetVerbosity(2)
+//   log := factorlog.Named("db.pool") // verbosity 2
+func Named(name string) *FactorLog {
+	if name == "" {
+		return std
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	return named(name)
+}
+
+// named does the work of Named. Must be called with registry locked.
+func named(name string) *FactorLog {
+	if name == "" {
+		return std
+	}
+
+	if l, ok := registry.loggers[name]; ok {
+		return l
+	}
+
+	parent := std
+	if dot := strings.LastIndexByte(name, '.'); dot >= 0 {
+		parent = named(name[:dot])
+	}
+
+	l := &FactorLog{name: name, parent: parent}
+	registry.loggers[name] = l
+	return l
+}
+
+// Loggers returns the standard logger followed by every logger
+// created by Named(), sorted by name.
+func Loggers() []*FactorLog {
+	registry.Lock()
+	loggers := make([]*FactorLog, 0, len(registry.loggers)+1)
+	for _, l := range registry.loggers {
+		loggers = append(loggers, l)
+	}
+	registry.Unlock()
+
+	sort.Slice(loggers, func(i, j int) bool {
+		return loggers[i].name < loggers[j].name
+	})
+	return append([]*FactorLog{std}, loggers...)
+}
+
+// lookupNamed returns the logger with the given name without
+// creating it.
+func lookupNamed(name string) (*FactorLog, bool) {
+	if name == "" {
+		return std, true
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	l, ok := registry.loggers[name]
+	return l, ok
+}
+
+// forgetNamed removes a logger and its descendants from the registry,
+// so the next call to Named creates them again. It is used by tests.
+func forgetNamed(name string) {
+	registry.Lock()
+	defer registry.Unlock()
+	for n := range registry.loggers {
+		if n == name || strings.HasPrefix(n, name+".") {
+			delete(registry.loggers, n)
+		}
+	}
+}
+
+// Parent returns the logger a named logger inherits its settings
+// from, or nil for any other logger.
+func (l *FactorLog) Parent() *FactorLog {
+	return l.cfg().parent
+}
+
+// Inherit drops every setting made on a named logger, so it goes
+// back to inheriting all of them from its parent.
+func (l *FactorLog) Inherit() {
+	l = l.cfg()
+	if l.parent == nil {
+		return
+	}
+
+	// Like Close, write the held back repeats and stop the sampler,
+	// which writes its last summary.
+	if l.owns(ownDedup) {
+		l.SetDedup(0)
+	}
+	if l.owns(ownSampling) {
+		l.SetSampling(nil)
+	}
+
+	l.mu.Lock()
+	var q *asyncQueue
+	if l.owns(ownAsync) {
+		q, _ = l.async.Load().(*asyncQueue)
+	}
+	atomic.StoreInt32(&l.own, 0)
+	l.mu.Unlock()
+
+	if q != nil {
+		q.close()
+	}
+}
+
+// owner returns the logger in the hierarchy that holds the given
+// setting for l: l itself unless it is a named logger that inherits it.
+func (l *FactorLog) owner(setting int32) *FactorLog {
+	for l.parent != nil && atomic.LoadInt32(&l.own)&setting == 0 {
+		l = l.parent
+	}
+	return l
+}
+
+// owns reports whether l holds the given setting itself.
+func (l *FactorLog) owns(setting int32) bool {
+	return l.parent == nil || atomic.LoadInt32(&l.own)&setting != 0
+}
+
+// setOwn marks the given setting as overridden on l.
+func (l *FactorLog) setOwn(setting int32) {
+	for {
+		own := atomic.LoadInt32(&l.own)
+		if own&setting != 0 || atomic.CompareAndSwapInt32(&l.own, own, own|setting) {
+			return
+		}
+	}
+}
+
+// claimSinks makes a named logger that inherits its sinks own a copy
+// of them, so they can be changed without affecting its parent.
+// Must be called with l.mu held.
+func (l *FactorLog) claimSinks() {
+	if l.owns(ownSinks) {
+		return
+	}
+	inherited := l.loadSinks()
+	sinks := make([]*Sink, len(inherited))
+	for i, s := range inherited {
+		sinks[i] = s.copy()
+	}
+	l.sinks.Store(sinks)
+	l.setOwn(ownSinks)
+}
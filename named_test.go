@@ -0,0 +1,102 @@
+package factorlog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNamed(t *testing.T) {
+	t.Cleanup(func() { forgetNamed("testnamed") })
+	if Named("") != std {
+		t.Fatal("expected the empty name to return the standard logger")
+	}
+
+	child := Named("testnamed.db.pool")
+	parent := Named("testnamed.db")
+	if Named("testnamed.db.pool") != child {
+		t.Fatal("expected Named to return the same logger for the same name")
+	}
+	if child.Parent() != parent || parent.Parent() != Named("testnamed") || Named("testnamed").Parent() != std {
+		t.Fatal("expected the hierarchy to follow the dots in the name")
+	}
+	if child.Name() != "testnamed.db.pool" {
+		t.Fatalf("expected name %q, got %q", "testnamed.db.pool", child.Name())
+	}
+
+	// Outputs set on the parent reach the child.
+	buf := &bytes.Buffer{}
+	parent.SetOutput(buf)
+	parent.SetFormatter(NewStdFormatter("%{Name}: %{Message}"))
+	child.Info("hey")
+	child.With("k", "v").Info("with")
+	if buf.String() != "testnamed.db.pool: hey\ntestnamed.db.pool: with\n" {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", "testnamed.db.pool: hey\ntestnamed.db.pool: with\n", buf.String())
+	}
+
+	// Severities set on the parent at runtime reach the child...
+	buf.Reset()
+	parent.SetSeverities(ERROR)
+	child.Info("should not appear")
+	if buf.Len() > 0 {
+		t.Fatal("expected the child to inherit the parent's severities")
+	}
+
+	// ...unless the child overrides them.
+	child.SetMinMaxSeverity(INFO, PANIC)
+	child.Info("hey")
+	parent.Info("should not appear")
+	if buf.String() != "testnamed.db.pool: hey\n" {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", "testnamed.db.pool: hey\n", buf.String())
+	}
+
+	// Verbosity is inherited too.
+	parent.SetVerbosity(3)
+	if !child.IsV(3) || child.IsV(4) {
+		t.Fatal("expected the child to inherit the parent's verbosity")
+	}
+	child.SetVerbosity(1)
+	if child.IsV(2) || !parent.IsV(3) {
+		t.Fatal("expected the child's verbosity to override the parent's")
+	}
+
+	// A child with its own output doesn't write to the parent's.
+	buf2 := &bytes.Buffer{}
+	buf.Reset()
+	child.SetOutput(buf2)
+	child.Error("hey")
+	if buf.Len() > 0 || buf2.String() != "testnamed.db.pool: hey\n" {
+		t.Fatalf("expected the child to write only to its own output, got %#v and %#v", buf.String(), buf2.String())
+	}
+
+	// Inherit goes back to the parent's settings.
+	child.Inherit()
+	buf.Reset()
+	buf2.Reset()
+	child.Info("should not appear")
+	child.Error("hey")
+	if buf.String() != "testnamed.db.pool: hey\n" || buf2.Len() > 0 || !child.IsV(3) {
+		t.Fatalf("expected the child to inherit everything again, got %#v and %#v", buf.String(), buf2.String())
+	}
+}
+
+func TestNamedAsync(t *testing.T) {
+	buf := &bytes.Buffer{}
+	parent := Named("testnamedasync")
+	child := Named("testnamedasync.child")
+	parent.SetOutput(buf)
+	parent.SetFormatter(NewStdFormatter("%{Message}"))
+	parent.SetAsync(10, OverflowBlock)
+	t.Cleanup(func() {
+		parent.Close()
+		forgetNamed("testnamedasync")
+	})
+
+	child.Info("hey")
+	child.Close() // only flushes, since the child inherits the queue
+	if buf.String() != "hey\n" {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", "hey\n", buf.String())
+	}
+	if parent.loadAsync() == nil {
+		t.Fatal("expected closing the child to leave the parent's queue alone")
+	}
+}
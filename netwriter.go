@@ -0,0 +1,461 @@
+package factorlog
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var errNetWriterFull = errors.New("factorlog: network writer buffer is full; record dropped")
+
+// netMinBackoff is the time between attempts to reconnect used when
+// MinBackoff isn't positive.
+const netMinBackoff = 100 * time.Millisecond
+
+// NetWriter is an io.Writer that sends records over a TCP, UDP or unix
+// socket and survives the connection going down. While it is down,
+// records are kept in a bounded memory buffer, which overflows to
+// spool files on disk if SpoolDir is set. A background goroutine
+// reconnects with exponential backoff and sends the buffered and
+// spooled records in order before new records are sent directly
+// again. Spooled records left by an earlier run are sent first.
+//
+// Writes only fail when a record has to be dropped because both the
+// memory buffer and the spool are full, and the first Write fails if
+// the spool can't be opened. Records replayed from the
+// spool may be sent twice if the writer is closed while replaying.
+// Set the fields before the first Write.
+// Example:
+//   w := factorlog.NewNetWriter("tcp", "logs.example.com:5000")
+//   w.SpoolDir = "/var/spool/myapp"
+//   defer w.Close()
+//   log := factorlog.New(w, factorlog.NewJSONFormatter())
+type NetWriter struct {
+	// MaxBuffer is the number of bytes of records kept in memory
+	// while disconnected. It defaults to 1MB.
+	MaxBuffer int
+	// SpoolDir is the directory records are written to once the
+	// memory buffer is full. If empty, they are dropped instead.
+	SpoolDir string
+	// MaxSpool is the number of bytes the spool may hold. 0 means
+	// no limit.
+	MaxSpool int64
+	// MinBackoff and MaxBackoff bound the time between attempts to
+	// reconnect, which doubles after each failure. They default to
+	// 100ms and 30s, and a MinBackoff of 0 is treated as 100ms.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// WriteTimeout bounds the time a write to the connection may
+	// take. A write that times out is treated like a disconnect, so
+	// a stalled peer doesn't block logging. It defaults to 5s.
+	WriteTimeout time.Duration
+
+	mu      sync.Mutex
+	network string
+	addr    string
+	conn    net.Conn // set while connected and nothing is waiting
+	started bool
+	running bool // the reconnect goroutine is running
+	closed  bool
+	ctx     context.Context // canceled by Close
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	queue   [][]byte
+	queued  int // bytes in queue
+	spool   *netSpool
+	dropped uint64
+}
+
+// NewNetWriter creates a NetWriter sending records to addr over
+// network, which is one of the networks accepted by net.Dial. It
+// connects in the background after the first Write.
+func NewNetWriter(network, addr string) *NetWriter {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &NetWriter{
+		MaxBuffer:    1 << 20,
+		MinBackoff:   netMinBackoff,
+		MaxBackoff:   30 * time.Second,
+		WriteTimeout: 5 * time.Second,
+		network:      network,
+		addr:         addr,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// Write sends p, or buffers it if the connection is down. If the
+// spool can't be opened, the first Write buffers p in memory, returns
+// the error, and the writer goes on without a spool.
+func (w *NetWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	var spoolErr error
+	if !w.started {
+		w.started = true
+		w.reconnect()
+		if w.SpoolDir != "" {
+			w.spool, spoolErr = openNetSpool(w.SpoolDir, w.MaxSpool)
+		}
+	}
+
+	if w.conn != nil {
+		if _, err := w.write(w.conn, p); err == nil {
+			return len(p), nil
+		}
+		w.conn.Close()
+		w.conn = nil
+		w.reconnect()
+	}
+
+	if !w.enqueue(p) {
+		atomic.AddUint64(&w.dropped, 1)
+		return 0, errNetWriterFull
+	}
+	return len(p), spoolErr
+}
+
+// enqueue buffers a copy of p in memory, or in the spool if the memory
+// buffer is full. Once anything is spooled, later records are spooled
+// too until the spool is empty again, so they are sent in order.
+func (w *NetWriter) enqueue(p []byte) bool {
+	spooling := w.spool != nil && !w.spool.empty()
+	if !spooling && w.queued+len(p) <= w.MaxBuffer {
+		w.queue = append(w.queue, append([]byte(nil), p...))
+		w.queued += len(p)
+		return true
+	}
+
+	return w.spool != nil && w.spool.append(p) == nil
+}
+
+// reconnect starts the goroutine that reconnects, unless it is
+// already running. w.mu must be held.
+func (w *NetWriter) reconnect() {
+	if w.running {
+		return
+	}
+	w.running = true
+	w.wg.Add(1)
+	go w.run()
+}
+
+func (w *NetWriter) run() {
+	defer w.wg.Done()
+
+	// A zero MinBackoff would redial in a busy loop while the
+	// server is down.
+	minBackoff := w.MinBackoff
+	if minBackoff <= 0 {
+		minBackoff = netMinBackoff
+	}
+
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	backoff := minBackoff
+	for {
+		conn, err := dialer.DialContext(w.ctx, w.network, w.addr)
+		if err == nil {
+			if w.replay(conn) {
+				return
+			}
+			backoff = minBackoff
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-w.ctx.Done():
+			return
+		}
+
+		if backoff *= 2; backoff > w.MaxBackoff {
+			backoff = w.MaxBackoff
+		}
+		if backoff < minBackoff {
+			backoff = minBackoff
+		}
+	}
+}
+
+// replay sends the buffered and spooled records over conn, oldest
+// first. It returns true once everything is sent and conn is in use
+// for new records, or if the writer was closed, and false if conn
+// failed.
+func (w *NetWriter) replay(conn net.Conn) bool {
+	for {
+		w.mu.Lock()
+		if w.closed {
+			w.mu.Unlock()
+			conn.Close()
+			return true
+		}
+
+		// Only this goroutine removes records, so the record
+		// stays at the front while it is sent without the lock.
+		var rec []byte
+		if len(w.queue) > 0 {
+			rec = w.queue[0]
+		} else if w.spool != nil {
+			rec = w.spool.peek()
+		}
+		if rec == nil {
+			w.conn = conn
+			w.running = false
+			w.mu.Unlock()
+			return true
+		}
+		w.mu.Unlock()
+
+		if _, err := w.write(conn, rec); err != nil {
+			conn.Close()
+			return false
+		}
+
+		w.mu.Lock()
+		if len(w.queue) > 0 {
+			w.queue[0] = nil
+			w.queue = w.queue[1:]
+			w.queued -= len(rec)
+		} else {
+			w.spool.pop()
+		}
+		w.mu.Unlock()
+	}
+}
+
+// write writes p to conn within WriteTimeout.
+func (w *NetWriter) write(conn net.Conn, p []byte) (int, error) {
+	if w.WriteTimeout > 0 {
+		conn.SetWriteDeadline(time.Now().Add(w.WriteTimeout))
+	}
+	return conn.Write(p)
+}
+
+// Dropped returns the number of records dropped because the memory
+// buffer and the spool were full.
+func (w *NetWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// Close closes the connection and stops reconnecting. Records still
+// in the memory buffer are written to the spool, if there is one, to
+// be sent by the next NetWriter using it.
+func (w *NetWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.cancel()
+	var err error
+	if w.conn != nil {
+		err = w.conn.Close()
+		w.conn = nil
+	}
+	w.mu.Unlock()
+
+	w.wg.Wait()
+
+	if w.spool != nil {
+		if perr := w.spool.prepend(w.queue); perr != nil && err == nil {
+			err = perr
+		}
+		w.spool.close()
+	}
+	w.queue = nil
+	return err
+}
+
+// netSpoolFileSize is the size at which a new spool file is started.
+const netSpoolFileSize = 4 << 20
+
+// netSpool is a queue of records in files named by a sequence number.
+// Each record is written as its length, a big endian uint32, followed
+// by its bytes.
+type netSpool struct {
+	dir   string
+	max   int64
+	size  int64   // bytes in the files
+	files []int64 // sequence numbers, oldest first
+	w     *os.File
+	wsize int64
+	r     *bufio.Reader
+	rf    *os.File
+	next  []byte // record read by peek
+}
+
+func openNetSpool(dir string, max int64) (*netSpool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &netSpool{dir: dir, max: max}
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".spool") {
+			continue
+		}
+		seq, err := strconv.ParseInt(strings.TrimSuffix(e.Name(), ".spool"), 10, 64)
+		if err != nil {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			s.size += info.Size()
+		}
+		s.files = append(s.files, seq)
+	}
+	sort.Slice(s.files, func(i, j int) bool { return s.files[i] < s.files[j] })
+	return s, nil
+}
+
+func (s *netSpool) path(seq int64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d.spool", seq))
+}
+
+func (s *netSpool) empty() bool {
+	return len(s.files) == 0
+}
+
+// append adds a record to the newest spool file, starting a new one
+// if there is none being written or it's full.
+func (s *netSpool) append(p []byte) error {
+	if s.max > 0 && s.size+int64(len(p))+4 > s.max {
+		return errNetWriterFull
+	}
+
+	if s.w == nil || s.wsize >= netSpoolFileSize {
+		seq := int64(1 << 32)
+		if len(s.files) > 0 {
+			seq = s.files[len(s.files)-1] + 1
+		}
+		f, err := os.OpenFile(s.path(seq), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return err
+		}
+		if s.w != nil {
+			s.w.Close()
+		}
+		s.w = f
+		s.wsize = 0
+		s.files = append(s.files, seq)
+	}
+
+	buf := make([]byte, 4, 4+len(p))
+	binary.BigEndian.PutUint32(buf, uint32(len(p)))
+	buf = append(buf, p...)
+	n, err := s.w.Write(buf)
+	s.wsize += int64(n)
+	s.size += int64(n)
+	return err
+}
+
+// peek returns the oldest record without removing it, or nil if the
+// spool is empty. Files that have been read to the end, or that are
+// cut short, are removed.
+func (s *netSpool) peek() []byte {
+	for s.next == nil && len(s.files) > 0 {
+		if s.rf == nil {
+			f, err := os.Open(s.path(s.files[0]))
+			if err != nil {
+				s.removeFirst()
+				continue
+			}
+			s.rf = f
+			s.r = bufio.NewReader(f)
+		}
+
+		var size [4]byte
+		if _, err := io.ReadFull(s.r, size[:]); err == nil {
+			rec := make([]byte, binary.BigEndian.Uint32(size[:]))
+			if _, err := io.ReadFull(s.r, rec); err == nil {
+				s.next = rec
+				break
+			}
+		}
+		s.removeFirst()
+	}
+	return s.next
+}
+
+// pop removes the record returned by peek.
+func (s *netSpool) pop() {
+	s.next = nil
+}
+
+// removeFirst deletes the oldest spool file.
+func (s *netSpool) removeFirst() {
+	if s.rf != nil {
+		s.rf.Close()
+		s.rf = nil
+		s.r = nil
+	}
+	if len(s.files) == 1 && s.w != nil {
+		s.w.Close()
+		s.w = nil
+	}
+
+	path := s.path(s.files[0])
+	if info, err := os.Stat(path); err == nil {
+		s.size -= info.Size()
+	}
+	os.Remove(path)
+	s.files = s.files[1:]
+}
+
+// prepend writes records to a new file placed before every other
+// spool file, so they are read first.
+func (s *netSpool) prepend(records [][]byte) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	seq := int64(1 << 32)
+	if len(s.files) > 0 {
+		seq = s.files[0] - 1
+	}
+	f, err := os.OpenFile(s.path(seq), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(f)
+	for _, rec := range records {
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(rec)))
+		bw.Write(size[:])
+		bw.Write(rec)
+	}
+	err = bw.Flush()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	s.files = append([]int64{seq}, s.files...)
+	return err
+}
+
+func (s *netSpool) close() {
+	if s.rf != nil {
+		s.rf.Close()
+	}
+	if s.w != nil {
+		s.w.Close()
+	}
+}
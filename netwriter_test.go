@@ -0,0 +1,187 @@
+package factorlog
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// freeAddr returns a local TCP address nothing is listening on.
+func freeAddr(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+// readNetLines accepts a connection on ln and reads n lines from it.
+func readNetLines(t *testing.T, ln net.Listener, n int) []string {
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var lines []string
+	r := bufio.NewReader(conn)
+	for len(lines) < n {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("after %d lines: %v", len(lines), err)
+		}
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+	return lines
+}
+
+func expectLines(t *testing.T, lines []string, from, to int) {
+	if len(lines) != to-from {
+		t.Fatalf("expected %d lines, got %d", to-from, len(lines))
+	}
+	for i, line := range lines {
+		if expect := fmt.Sprintf("line %d", from+i); line != expect {
+			t.Fatalf("expected %q, got %q", expect, line)
+		}
+	}
+}
+
+func TestNetWriterReconnect(t *testing.T) {
+	addr := freeAddr(t)
+	w := NewNetWriter("tcp", addr)
+	w.MaxBuffer = 50
+	w.SpoolDir = t.TempDir()
+	w.MinBackoff = 10 * time.Millisecond
+	w.MaxBackoff = 20 * time.Millisecond
+	defer w.Close()
+	log := New(w, NewStdFormatter("%{Message}"))
+
+	// Nothing is listening yet, so these are buffered and then spooled.
+	for i := 0; i < 20; i++ {
+		log.Infof("line %d", i)
+	}
+	if entries, _ := os.ReadDir(w.SpoolDir); len(entries) == 0 {
+		t.Fatal("expected records to overflow to the spool")
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	log.Info("line 20")
+	expectLines(t, readNetLines(t, ln, 21), 0, 21)
+
+	if entries, _ := os.ReadDir(w.SpoolDir); len(entries) != 0 {
+		t.Fatalf("expected the spool to be empty after replay, got %d files", len(entries))
+	}
+}
+
+func TestNetWriterSpoolAcrossRuns(t *testing.T) {
+	dir := t.TempDir()
+	w := NewNetWriter("tcp", freeAddr(t))
+	w.MaxBuffer = 30
+	w.SpoolDir = dir
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(w, "line %d\n", i)
+	}
+	w.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	w = NewNetWriter("tcp", ln.Addr().String())
+	w.SpoolDir = dir
+	w.MinBackoff = 10 * time.Millisecond
+	defer w.Close()
+	fmt.Fprintf(w, "line 10\n")
+	expectLines(t, readNetLines(t, ln, 11), 0, 11)
+}
+
+func TestNetWriterDrop(t *testing.T) {
+	w := NewNetWriter("tcp", freeAddr(t))
+	w.MaxBuffer = 10
+	defer w.Close()
+
+	if _, err := w.Write([]byte("0123456789")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("x")); err == nil || w.Dropped() != 1 {
+		t.Fatalf("expected the record to be dropped, got %v and %d", err, w.Dropped())
+	}
+}
+
+func TestNetWriterSpoolError(t *testing.T) {
+	// A spool directory under a regular file can't be created.
+	file := t.TempDir() + "/file"
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	w := NewNetWriter("tcp", ln.Addr().String())
+	w.SpoolDir = file + "/spool"
+	defer w.Close()
+
+	if _, err := w.Write([]byte("line 0\n")); err == nil {
+		t.Fatal("expected the spool error")
+	}
+	// The writer still connects without a spool, and the first
+	// record was buffered anyway.
+	for i := 1; i < 3; i++ {
+		if _, err := fmt.Fprintf(w, "line %d\n", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectLines(t, readNetLines(t, ln, 3), 0, 3)
+}
+
+func TestNetWriterStalled(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	// Accept the connection but never read from it.
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(10 * time.Second)
+		}
+	}()
+
+	w := NewNetWriter("tcp", ln.Addr().String())
+	w.WriteTimeout = 50 * time.Millisecond
+	w.MaxBuffer = 1 << 30
+	defer w.Close()
+
+	record := make([]byte, 1<<20)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// Enough to fill the socket buffers of both ends.
+		for i := 0; i < 64; i++ {
+			w.Write(record)
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected writes to a stalled peer to time out")
+	}
+}
@@ -0,0 +1,111 @@
+package factorlog
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// ReopenFile is an io.Writer that writes to a file and opens it again
+// by path when the process receives SIGHUP or Reopen() is called. This
+// lets an external tool like logrotate rename the file and signal the
+// process, without needing copytruncate:
+//   f, err := factorlog.NewReopenFile("/var/log/app.log")
+//   if err != nil {
+//     ...
+//   }
+//   defer f.Close()
+//   log := factorlog.New(f, factorlog.NewStdFormatter("%{Date} %{Time} %{Message}"))
+// The new file is opened before the old one is closed, and the handles
+// are swapped under the same lock that serializes writes, so every
+// record is written whole to either the old or the new file.
+type ReopenFile struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	sig  chan os.Signal
+	done chan struct{}
+}
+
+// NewReopenFile opens path for appending, creating it if needed, and
+// starts listening for SIGHUP.
+func NewReopenFile(path string) (*ReopenFile, error) {
+	file, err := openAppend(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &ReopenFile{
+		path: path,
+		file: file,
+		sig:  make(chan os.Signal, 1),
+		done: make(chan struct{}),
+	}
+	signal.Notify(f.sig, syscall.SIGHUP)
+	go f.listen()
+	return f, nil
+}
+
+func openAppend(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+}
+
+func (f *ReopenFile) listen() {
+	for {
+		select {
+		case <-f.sig:
+			f.Reopen()
+		case <-f.done:
+			return
+		}
+	}
+}
+
+// Write writes p to the current file.
+func (f *ReopenFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	return f.file.Write(p)
+}
+
+// Reopen opens the path again and switches writes to it. If the path
+// can't be opened, writes keep going to the current file and the error
+// is returned.
+func (f *ReopenFile) Reopen() error {
+	file, err := openAppend(f.path)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	old := f.file
+	if old != nil {
+		f.file = file
+	}
+	f.mu.Unlock()
+
+	if old == nil {
+		file.Close()
+		return os.ErrClosed
+	}
+	return old.Close()
+}
+
+// Close stops listening for SIGHUP and closes the file.
+func (f *ReopenFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+
+	signal.Stop(f.sig)
+	close(f.done)
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
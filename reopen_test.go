@@ -0,0 +1,66 @@
+package factorlog
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReopenFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	f, err := NewReopenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	log := New(f, NewStdFormatter("%{Message}"))
+
+	log.Info("before")
+	os.Rename(path, path+".1")
+	log.Info("after rename")
+	if err := f.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	log.Info("after reopen")
+
+	rotated, _ := os.ReadFile(path + ".1")
+	current, _ := os.ReadFile(path)
+	if string(rotated) != "before\nafter rename\n" || string(current) != "after reopen\n" {
+		t.Fatalf("expected writes to move to the new file on Reopen, got %q and %q", rotated, current)
+	}
+
+	f.Close()
+	if _, err := f.Write([]byte("x")); err == nil {
+		t.Fatal("expected an error writing to a closed file")
+	}
+	if err := f.Reopen(); err == nil {
+		t.Fatal("expected an error reopening a closed file")
+	}
+}
+
+func TestReopenFileSignal(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	f, err := NewReopenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	p, _ := os.FindProcess(os.Getpid())
+	os.Rename(path, path+".1")
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		t.Skipf("can't send SIGHUP: %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("expected SIGHUP to reopen the file")
+}
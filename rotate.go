@@ -0,0 +1,350 @@
+package factorlog
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotateTimeLayout is the timestamp added to the names of rotated files.
+const rotateTimeLayout = "2006-01-02T15-04-05.000"
+
+// RotatingFile is an io.Writer that writes to a file and rotates it
+// when it grows past MaxSize, when Interval elapses, or both. The
+// rotated file is renamed to include the time it was rotated, e.g.
+// app.log becomes app-2006-01-02T15-04-05.000.log, and a new app.log
+// is started. Rotated files can be gzipped and pruned in the background.
+//
+// Each Write is written whole to a single file, so a record is never
+// split or lost across a rotation, even when several goroutines log
+// at once. Set the fields before the first Write.
+// Example:
+//   f := factorlog.NewRotatingFile("/var/log/app.log")
+//   f.MaxSize = 100 << 20
+//   f.Interval = 24 * time.Hour
+//   f.MaxBackups = 7
+//   f.Compress = true
+//   defer f.Close()
+//   log := factorlog.New(f, factorlog.NewStdFormatter("%{Date} %{Time} %{Message}"))
+type RotatingFile struct {
+	// Filename is the file being written to. It's created with its
+	// directory if it doesn't exist, and appended to if it does.
+	Filename string
+	// MaxSize is the size in bytes a file may reach before it is
+	// rotated. 0 turns off rotation by size.
+	MaxSize int64
+	// Interval rotates the file whenever the wall clock crosses a
+	// multiple of it (e.g. at midnight UTC for 24h). 0 turns off
+	// rotation by time.
+	Interval time.Duration
+	// MaxAge removes rotated files older than it. 0 keeps them
+	// regardless of age.
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files to keep. 0 keeps
+	// them all.
+	MaxBackups int
+	// Compress gzips rotated files.
+	Compress bool
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	next   time.Time // when the file is due to rotate by time
+	millCh chan struct{}
+	millWg sync.WaitGroup
+
+	now func() time.Time // for testing
+}
+
+// NewRotatingFile creates a RotatingFile writing to filename. The file
+// is opened on the first Write.
+func NewRotatingFile(filename string) *RotatingFile {
+	return &RotatingFile{Filename: filename}
+}
+
+// Write writes p to the file, rotating it first if it is due.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if r.size > 0 && r.due(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the file now, whether or not it is due.
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return err
+		}
+	}
+	return r.rotate()
+}
+
+// Close closes the file and waits for rotated files to be compressed
+// and pruned. A later Write opens the file again.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+	millCh := r.millCh
+	r.millCh = nil
+	r.mu.Unlock()
+
+	if millCh != nil {
+		close(millCh)
+		r.millWg.Wait()
+	}
+	return err
+}
+
+func (r *RotatingFile) timeNow() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+// due reports whether the file must be rotated before writing n bytes.
+func (r *RotatingFile) due(n int64) bool {
+	if r.MaxSize > 0 && r.size+n > r.MaxSize {
+		return true
+	}
+	return r.Interval > 0 && !r.timeNow().Before(r.next)
+}
+
+// open opens Filename for appending. The time of the next rotation is
+// based on when an existing file was last written, so a file left
+// over from an earlier interval is rotated on the first Write.
+func (r *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.Filename), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(r.Filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	r.file = f
+	r.size = info.Size()
+	start := r.timeNow()
+	if r.size > 0 {
+		start = info.ModTime()
+	}
+	if r.Interval > 0 {
+		r.next = start.Truncate(r.Interval).Add(r.Interval)
+	}
+	return nil
+}
+
+// rotate renames the current file and opens a new one. r.mu must be held.
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+
+	if err := os.Rename(r.Filename, r.backupName(r.timeNow())); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := r.open(); err != nil {
+		return err
+	}
+
+	if r.Compress || r.MaxAge > 0 || r.MaxBackups > 0 {
+		if r.millCh == nil {
+			r.millCh = make(chan struct{}, 1)
+			r.millWg.Add(1)
+			go r.mill(r.millCh)
+		}
+		select {
+		case r.millCh <- struct{}{}:
+		default:
+			// The goroutine already has work pending.
+		}
+	}
+	return nil
+}
+
+// backupName returns an unused name for a file rotated at t. Files
+// rotated within the same millisecond get a counter after the time.
+func (r *RotatingFile) backupName(t time.Time) string {
+	dir, prefix, ext := r.nameParts()
+	stamp := t.Format(rotateTimeLayout)
+	for i := 0; ; i++ {
+		name := prefix + stamp
+		if i > 0 {
+			name += "." + strconv.Itoa(i)
+		}
+		name = filepath.Join(dir, name+ext)
+		if !exists(name) && !exists(name+".gz") {
+			return name
+		}
+	}
+}
+
+// nameParts splits Filename into its directory, the prefix of rotated
+// files (the base name without extension, followed by a dash) and
+// its extension.
+func (r *RotatingFile) nameParts() (dir, prefix, ext string) {
+	dir, base := filepath.Split(r.Filename)
+	ext = filepath.Ext(base)
+	return dir, strings.TrimSuffix(base, ext) + "-", ext
+}
+
+func exists(name string) bool {
+	_, err := os.Lstat(name)
+	return err == nil
+}
+
+// backup is a rotated file found on disk.
+type backup struct {
+	path    string
+	t       time.Time
+	counter int
+}
+
+// backups returns the rotated files of r, newest first.
+func (r *RotatingFile) backups() ([]backup, error) {
+	dir, prefix, ext := r.nameParts()
+	if dir == "" {
+		dir = "."
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []backup
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".gz")
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+
+		stamp := strings.TrimSuffix(name[len(prefix):], ext)
+		if len(stamp) < len(rotateTimeLayout) {
+			continue
+		}
+		t, err := time.ParseInLocation(rotateTimeLayout, stamp[:len(rotateTimeLayout)], time.Local)
+		if err != nil {
+			continue
+		}
+		counter := 0
+		if rest := stamp[len(rotateTimeLayout):]; rest != "" {
+			if rest[0] != '.' {
+				continue
+			}
+			if counter, err = strconv.Atoi(rest[1:]); err != nil {
+				continue
+			}
+		}
+
+		backups = append(backups, backup{filepath.Join(dir, e.Name()), t, counter})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].t.Equal(backups[j].t) {
+			return backups[i].t.After(backups[j].t)
+		}
+		return backups[i].counter > backups[j].counter
+	})
+	return backups, nil
+}
+
+// mill compresses and prunes rotated files each time it is signaled,
+// until ch is closed.
+func (r *RotatingFile) mill(ch chan struct{}) {
+	defer r.millWg.Done()
+	for range ch {
+		r.millOnce()
+	}
+}
+
+func (r *RotatingFile) millOnce() {
+	backups, err := r.backups()
+	if err != nil {
+		return
+	}
+
+	cutoff := r.timeNow().Add(-r.MaxAge)
+	for i, b := range backups {
+		if (r.MaxBackups > 0 && i >= r.MaxBackups) || (r.MaxAge > 0 && b.t.Before(cutoff)) {
+			os.Remove(b.path)
+			continue
+		}
+
+		if r.Compress && !strings.HasSuffix(b.path, ".gz") {
+			compressFile(b.path)
+		}
+	}
+}
+
+// compressFile gzips src to src.gz and removes src. The compressed
+// file is written under a temporary name first, so a partial file is
+// never mistaken for a rotated one.
+func compressFile(src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := src + ".gz.tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if cerr := gz.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, src+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Remove(src)
+}
@@ -0,0 +1,168 @@
+package factorlog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// readLines returns the lines of every file in dir, gunzipping
+// those that are compressed.
+func readLines(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	for _, e := range entries {
+		f, err := os.Open(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r io.Reader = f
+		if strings.HasSuffix(e.Name(), ".gz") {
+			if r, err = gzip.NewReader(f); err != nil {
+				t.Fatalf("%s: %v", e.Name(), err)
+			}
+		}
+		s := bufio.NewScanner(r)
+		for s.Scan() {
+			lines = append(lines, s.Text())
+		}
+		f.Close()
+	}
+	return lines
+}
+
+func TestRotatingFileSize(t *testing.T) {
+	dir := t.TempDir()
+	f := NewRotatingFile(filepath.Join(dir, "app.log"))
+	f.MaxSize = 10
+
+	for _, s := range []string{"12345\n", "12345\n", "1234567890abc\n", "1\n"} {
+		f.Write([]byte(s))
+	}
+	f.Close()
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 files, got %d", len(entries))
+	}
+	current, _ := os.ReadFile(filepath.Join(dir, "app.log"))
+	if string(current) != "1\n" {
+		t.Fatalf("expected the current file to hold the last write, got %q", current)
+	}
+	for _, e := range entries {
+		if e.Name() != "app.log" && (!strings.HasPrefix(e.Name(), "app-") || !strings.HasSuffix(e.Name(), ".log")) {
+			t.Fatalf("unexpected rotated file name %q", e.Name())
+		}
+	}
+}
+
+func TestRotatingFileInterval(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2014, 1, 1, 10, 30, 0, 0, time.UTC)
+	f := NewRotatingFile(filepath.Join(dir, "app.log"))
+	f.Interval = time.Hour
+	f.now = func() time.Time { return now }
+
+	f.Write([]byte("a\n"))
+	now = now.Add(20 * time.Minute)
+	f.Write([]byte("b\n"))
+	now = now.Add(20 * time.Minute) // 11:10, past the hour
+	f.Write([]byte("c\n"))
+	f.Close()
+
+	current, _ := os.ReadFile(filepath.Join(dir, "app.log"))
+	rotated, _ := os.ReadFile(filepath.Join(dir, "app-"+now.Local().Format(rotateTimeLayout)+".log"))
+	if string(current) != "c\n" || string(rotated) != "a\nb\n" {
+		t.Fatalf("expected the file to rotate on the hour, got %q and %q", rotated, current)
+	}
+}
+
+func TestRotatingFilePrune(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2014, 1, 1, 10, 0, 0, 0, time.Local)
+	f := NewRotatingFile(filepath.Join(dir, "app.log"))
+	f.MaxBackups = 2
+	f.MaxAge = 5 * time.Hour
+	f.Compress = true
+	f.now = func() time.Time { return now }
+
+	// An unrelated file is left alone.
+	os.WriteFile(filepath.Join(dir, "app-notes.log"), []byte("x"), 0644)
+
+	for i := 0; i < 4; i++ {
+		fmt.Fprintf(f, "%d\n", i)
+		f.Rotate()
+		now = now.Add(time.Hour)
+	}
+	f.Close()
+
+	backups, err := f.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 rotated files, got %d", len(backups))
+	}
+	for _, b := range backups {
+		if !strings.HasSuffix(b.path, ".log.gz") {
+			t.Fatalf("expected %s to be compressed", b.path)
+		}
+	}
+	if lines := readLines(t, dir); strings.Join(lines, ",") != "2,3,x" {
+		t.Fatalf("expected the two newest files to be kept, got %v", lines)
+	}
+
+	// Pruning by age.
+	f.MaxBackups = 0
+	now = now.Add(4 * time.Hour) // the file rotated at 12:00 is now 6h old
+	f.Write([]byte("4\n"))
+	f.Rotate()
+	f.Close()
+	if lines := readLines(t, dir); strings.Join(lines, ",") != "3,4,x" {
+		t.Fatalf("expected files older than MaxAge to be removed, got %v", lines)
+	}
+}
+
+func TestRotatingFileConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	f := NewRotatingFile(filepath.Join(dir, "app.log"))
+	f.MaxSize = 512
+	f.Compress = true
+	log := New(f, NewStdFormatter("%{Message}"))
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				log.Infof("goroutine %d line %d", g, i)
+			}
+		}(g)
+	}
+	wg.Wait()
+	f.Close()
+
+	lines := readLines(t, dir)
+	if len(lines) != 8*200 {
+		t.Fatalf("expected %d lines, got %d", 8*200, len(lines))
+	}
+	seen := map[string]bool{}
+	for _, line := range lines {
+		if seen[line] || !strings.HasPrefix(line, "goroutine ") {
+			t.Fatalf("unexpected line %q", line)
+		}
+		seen[line] = true
+	}
+}
@@ -0,0 +1,288 @@
+package factorlog
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sampling limits the number of records a logger writes, so a hot
+// code path can't flood its outputs. Records that aren't written are
+// counted, and a summary can be written periodically. FATAL and PANIC
+// records are never suppressed. See FactorLog.SetSampling().
+type Sampling struct {
+	// First records with the same message template are written in
+	// each Interval, then only every Thereafter-th one, or none if
+	// Thereafter is 0. The template is the format of Printf style
+	// calls; other calls are counted per call site, so messages
+	// with IDs or times in them don't each get their own count.
+	// Leave First and Thereafter at 0 to not sample by template.
+	// Interval defaults to one second.
+	Interval   time.Duration
+	First      int
+	Thereafter int
+
+	// Limits caps the rate of records of each severity with a
+	// token bucket.
+	Limits map[Severity]RateLimit
+
+	// SummaryInterval is how often a record saying how many records
+	// were suppressed is written, if any were. 0 disables it. The
+	// summary has SummarySeverity, WARN by default, and the count
+	// in a "suppressed" field.
+	SummaryInterval time.Duration
+	SummarySeverity Severity
+}
+
+// RateLimit is a token bucket: up to Burst records can be written at
+// once, and it refills at Rate records per second.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// sampler holds the state of a Sampling.
+type sampler struct {
+	suppressed uint64 // first for 64-bit alignment of atomic access
+	total      uint64
+
+	conf Sampling
+
+	mu      sync.Mutex
+	start   time.Time            // start of the current interval
+	counts  map[sampleKey]uint64 // records seen per template this interval
+	buckets map[Severity]*bucket
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// SetSampling sets how this logger samples and rate limits records,
+// replacing any previous sampling. A nil Sampling turns it off. The
+// summary goes through the same severities as other records, so it
+// isn't written if SummarySeverity is turned off for the logger or
+// its sinks, although the records are still counted by Suppressed().
+// Example:
+//   log.SetSampling(&factorlog.Sampling{
+//     First:      10,
+//     Thereafter: 100,
+//     Limits:     map[factorlog.Severity]factorlog.RateLimit{factorlog.ERROR: {Rate: 50, Burst: 100}},
+//     SummaryInterval: time.Minute,
+//   })
+func (l *FactorLog) SetSampling(s *Sampling) {
+	l = l.cfg()
+	var smp *sampler
+	if s != nil {
+		smp = newSampler(*s)
+	}
+
+	l.mu.Lock()
+	old, _ := l.sampling.Load().(*sampler)
+	l.sampling.Store(smp)
+	l.setOwn(ownSampling)
+	l.mu.Unlock()
+
+	if old != nil {
+		l.stopSampler(old)
+	}
+	if smp != nil && smp.conf.SummaryInterval > 0 {
+		go l.summarize(smp)
+	}
+}
+
+// Suppressed returns the number of records this logger didn't write
+// because of sampling, since the last call to SetSampling.
+func (l *FactorLog) Suppressed() uint64 {
+	if s := l.cfg().loadSampler(); s != nil {
+		return atomic.LoadUint64(&s.total)
+	}
+	return 0
+}
+
+// needsCaller reports whether s counts records per call site. s may
+// be nil.
+func (s *sampler) needsCaller() bool {
+	return s != nil && (s.conf.First > 0 || s.conf.Thereafter > 0)
+}
+
+func (l *FactorLog) loadSampler() *sampler {
+	s, _ := l.owner(ownSampling).sampling.Load().(*sampler)
+	return s
+}
+
+func newSampler(conf Sampling) *sampler {
+	if conf.Interval <= 0 {
+		conf.Interval = time.Second
+	}
+	if conf.SummarySeverity == 0 {
+		conf.SummarySeverity = WARN
+	}
+
+	s := &sampler{
+		conf:    conf,
+		counts:  make(map[sampleKey]uint64),
+		buckets: make(map[Severity]*bucket),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	for sev, limit := range conf.Limits {
+		s.buckets[sev] = &bucket{tokens: float64(limit.Burst)}
+	}
+	return s
+}
+
+// sample reports whether a record should be written, counting it as
+// suppressed if not.
+func (s *sampler) sample(context *LogContext) bool {
+	if context.Severity&(FATAL|PANIC) != 0 {
+		return true
+	}
+
+	s.mu.Lock()
+	ok := s.allowTemplate(context) && s.allowRate(context)
+	s.mu.Unlock()
+
+	if !ok {
+		atomic.AddUint64(&s.suppressed, 1)
+		atomic.AddUint64(&s.total, 1)
+	}
+	return ok
+}
+
+// allowTemplate applies First and Thereafter. Must be called with
+// s.mu held.
+func (s *sampler) allowTemplate(context *LogContext) bool {
+	if s.conf.First <= 0 && s.conf.Thereafter <= 0 {
+		return true
+	}
+
+	if context.Time.Sub(s.start) >= s.conf.Interval || context.Time.Before(s.start) {
+		s.start = context.Time
+		s.counts = make(map[sampleKey]uint64)
+	}
+
+	key := templateOf(context)
+	n := s.counts[key] + 1
+	s.counts[key] = n
+	if n <= uint64(s.conf.First) {
+		return true
+	}
+	return s.conf.Thereafter > 0 && (n-uint64(s.conf.First))%uint64(s.conf.Thereafter) == 0
+}
+
+// allowRate takes a token from the bucket of the record's severity.
+// Must be called with s.mu held.
+func (s *sampler) allowRate(context *LogContext) bool {
+	b := s.buckets[context.Severity]
+	if b == nil {
+		return true
+	}
+
+	limit := s.conf.Limits[context.Severity]
+	if !b.last.IsZero() && context.Time.After(b.last) {
+		b.tokens += context.Time.Sub(b.last).Seconds() * limit.Rate
+		if b.tokens > float64(limit.Burst) {
+			b.tokens = float64(limit.Burst)
+		}
+	}
+	if b.last.IsZero() || context.Time.After(b.last) {
+		b.last = context.Time
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// sampleKey identifies the template of a record: its format, or the
+// call site of a record without one.
+type sampleKey struct {
+	format string
+	pc     uintptr
+}
+
+// templateOf returns the template of a record. The message is only
+// used if the call site isn't known.
+func templateOf(context *LogContext) sampleKey {
+	if context.Format != nil {
+		return sampleKey{format: *context.Format}
+	}
+	if context.PC != 0 {
+		return sampleKey{pc: context.PC}
+	}
+	return sampleKey{format: messageTemplate(context)}
+}
+
+// messageTemplate returns the message of a record without a format.
+func messageTemplate(context *LogContext) string {
+	if len(context.Args) == 1 {
+		if s, ok := context.Args[0].(string); ok {
+			return s
+		}
+	}
+	return fmt.Sprint(context.Args...)
+}
+
+// summarize writes a summary of s every SummaryInterval until s is
+// stopped.
+func (l *FactorLog) summarize(s *sampler) {
+	defer close(s.done)
+	t := time.NewTicker(s.conf.SummaryInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			l.writeSummary(s)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// stopSampler stops the summary goroutine of s, if it has one, and
+// writes a last summary.
+func (l *FactorLog) stopSampler(s *sampler) {
+	if s.conf.SummaryInterval <= 0 {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	l.writeSummary(s)
+}
+
+// writeSummary writes a record with the number of records suppressed
+// by s since the last summary, if there are any.
+func (l *FactorLog) writeSummary(s *sampler) {
+	n := atomic.SwapUint64(&s.suppressed, 0)
+	if n == 0 {
+		return
+	}
+
+	sev := s.conf.SummarySeverity
+	sinks, _ := l.sinksFor(sev)
+	if sinks == nil {
+		return
+	}
+	l.dispatch(sinks, LogContext{
+		Time:     time.Now(),
+		Severity: sev,
+		Pid:      pid,
+		Args:     []interface{}{fmt.Sprintf("%d records suppressed", n)},
+		Fields:   []Field{{"suppressed", n}},
+		Name:     l.name,
+	}, nil)
+}
+
+// SetSampling sets the sampling of the standard logger.
+// See FactorLog.SetSampling().
+func SetSampling(s *Sampling) {
+	std.SetSampling(s)
+}
@@ -0,0 +1,101 @@
+package factorlog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSamplingTemplate(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(buf, NewStdFormatter("%{Message}"))
+	log.SetSampling(&Sampling{First: 2, Thereafter: 3, Interval: time.Hour})
+
+	for i := 1; i <= 8; i++ {
+		log.Errorf("error %d", i)
+		log.Info("other")
+	}
+	// 1 and 2 are written, then every 3rd: 5 and 8.
+	expect := "error 1\nother\nerror 2\nother\nerror 5\nother\nerror 8\nother\n"
+	if buf.String() != expect {
+		t.Fatalf("expected %#v, got %#v", expect, buf.String())
+	}
+	if log.Suppressed() != 8 {
+		t.Fatalf("expected 8 suppressed records, got %d", log.Suppressed())
+	}
+}
+
+func TestSamplingRateLimit(t *testing.T) {
+	s := newSampler(Sampling{Limits: map[Severity]RateLimit{ERROR: {Rate: 2, Burst: 3}}})
+	now := time.Now()
+	allowed := func(sev Severity, d time.Duration) bool {
+		return s.sample(&LogContext{Time: now.Add(d), Severity: sev})
+	}
+
+	for i := 0; i < 3; i++ {
+		if !allowed(ERROR, 0) {
+			t.Fatal("expected the burst to be allowed")
+		}
+	}
+	if allowed(ERROR, 0) {
+		t.Fatal("expected the empty bucket to suppress the record")
+	}
+	if !allowed(INFO, 0) || !allowed(FATAL, 0) {
+		t.Fatal("expected other severities not to be limited")
+	}
+	// Two tokens a second: after half a second, one more record.
+	if !allowed(ERROR, 500*time.Millisecond) || allowed(ERROR, 500*time.Millisecond) {
+		t.Fatal("expected the bucket to refill at its rate")
+	}
+}
+
+func TestSamplingCallSite(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(buf, NewStdFormatter("%{Message}"))
+	log.SetSampling(&Sampling{First: 1, Interval: time.Hour})
+
+	// Messages from one call site are counted together, however
+	// they differ.
+	for i := 0; i < 3; i++ {
+		log.Info("request ", i)
+	}
+	s := log.loadSampler()
+	if buf.String() != "request 0\n" || len(s.counts) != 1 {
+		t.Fatalf("expected one count for the call site, got %#v and %d counts", buf.String(), len(s.counts))
+	}
+}
+
+func TestSamplingSummary(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(buf, NewStdFormatter("%{SEVERITY} %{Message} %{Fields}"))
+	log.SetSampling(&Sampling{First: 1, Interval: time.Hour, SummaryInterval: time.Hour})
+
+	for i := 0; i < 3; i++ {
+		log.Error("hey")
+	}
+	log.Close()
+	expect := "ERROR hey \nWARN 2 records suppressed suppressed=2\n"
+	if buf.String() != expect {
+		t.Fatalf("expected %#v, got %#v", expect, buf.String())
+	}
+}
+
+func TestSamplingInherit(t *testing.T) {
+	t.Cleanup(func() { forgetNamed("testsampling") })
+	buf := &bytes.Buffer{}
+	parent := Named("testsampling")
+	child := Named("testsampling.child")
+	parent.SetOutput(buf)
+	parent.SetFormatter(NewStdFormatter("%{SEVERITY} %{Message}"))
+	child.SetSampling(&Sampling{First: 1, Interval: time.Hour, SummaryInterval: time.Hour})
+
+	for i := 0; i < 2; i++ {
+		child.Error("hey")
+	}
+	child.Inherit()
+	child.Error("hey")
+	expect := "ERROR hey\nWARN 1 records suppressed\nERROR hey\n"
+	if buf.String() != expect {
+		t.Fatalf("expected Inherit to stop the sampler, got %#v", buf.String())
+	}
+}
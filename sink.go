@@ -0,0 +1,267 @@
+package factorlog
+
+import (
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// Sink is one output of a FactorLog: a writer, the formatter used
+// to format records for it and the severities it accepts. A logger
+// can write each record to several sinks, e.g. colored INFO and above
+// to the console and everything as JSON to a file:
+//   console := factorlog.NewSink(os.Stderr, factorlog.NewStdFormatter(`%{Color "red" "ERROR"}%{Message}%{Color "reset"}`))
+//   console.SetMinMaxSeverity(factorlog.INFO, factorlog.PANIC)
+//   file := factorlog.NewSink(f, factorlog.NewJSONFormatter())
+//   log := factorlog.NewMulti(console, file)
+// Writes to a sink are serialized, so a sink may be shared by
+// several loggers.
+type Sink struct {
+	errors uint64 // failed writes; first for 64-bit alignment of atomic access
+
+	mu         *sync.Mutex  // serializes writes to out; shared by copies of the sink
+	output     atomic.Value // *sinkOutput
+	severities Severity
+}
+
+// sinkOutput is the writer and formatter of a sink. It is replaced as
+// a whole, so SetOutput and SetFormatter can change a sink while
+// records are written to it.
+type sinkOutput struct {
+	out        io.Writer
+	formatter  Formatter
+	appender   AppendFormatter // set if formatter is one
+	concurrent bool            // formatter may be called without holding mu
+}
+
+// defaultFormat is the format of the standard logger, and of sinks
+// created without a formatter.
+const defaultFormat = "%{Date} %{Time} %{Message}"
+
+// NewSink creates a sink that accepts every severity. A nil out
+// writes to os.Stderr, and a nil formatter uses the format of the
+// standard logger.
+func NewSink(out io.Writer, formatter Formatter) *Sink {
+	s := &Sink{
+		mu:         &sync.Mutex{},
+		severities: Severity(maxint32),
+	}
+	s.setOutput(out, formatter)
+	return s
+}
+
+// setOutput replaces the writer and the formatter of the sink.
+func (s *Sink) setOutput(out io.Writer, formatter Formatter) {
+	if out == nil {
+		out = os.Stderr
+	}
+	if formatter == nil {
+		formatter = NewStdFormatter(defaultFormat)
+	}
+	appender, _ := formatter.(AppendFormatter)
+	s.output.Store(&sinkOutput{
+		out:        out,
+		formatter:  formatter,
+		appender:   appender,
+		concurrent: isConcurrentFormatter(formatter),
+	})
+}
+
+// replace changes the writer or the formatter of the sink in place,
+// keeping the ones that are nil. It takes the lock of the sink, so it
+// waits for a record being written to the old writer, and records
+// written after it returns use the new ones.
+func (s *Sink) replace(out io.Writer, formatter Formatter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o := s.load()
+	if out == nil {
+		out = o.out
+	}
+	if formatter == nil {
+		formatter = o.formatter
+	}
+	s.setOutput(out, formatter)
+}
+
+// copy returns a new sink with the same writer, formatter and
+// severities as s, and that shares its lock, so both can write to the
+// writer. Named loggers copy the sinks they inherit before changing them.
+func (s *Sink) copy() *Sink {
+	c := &Sink{mu: s.mu}
+	c.output.Store(s.load())
+	c.severities.set(s.severities.get())
+	return c
+}
+
+func (s *Sink) load() *sinkOutput {
+	return s.output.Load().(*sinkOutput)
+}
+
+// SetSeverities sets which severities this sink will output for.
+// The logger's own severities are checked first, so a record has
+// to pass both to be written to the sink.
+func (s *Sink) SetSeverities(sev Severity) {
+	s.severities.set(sev)
+}
+
+// SetMinMaxSeverity sets the minimum and maximum severities this
+// sink will output for.
+func (s *Sink) SetMinMaxSeverity(min Severity, max Severity) {
+	s.severities.set(minMaxSeverities(min, max))
+}
+
+// Writer returns the writer of this sink.
+func (s *Sink) Writer() io.Writer {
+	return s.load().out
+}
+
+// Formatter returns the formatter of this sink.
+func (s *Sink) Formatter() Formatter {
+	return s.load().formatter
+}
+
+// WriteErrors returns the number of records this sink failed to write.
+func (s *Sink) WriteErrors() uint64 {
+	return atomic.LoadUint64(&s.errors)
+}
+
+// writeSinks formats and writes a record to each sink that accepts its
+// severity, followed by stack if it isn't nil. Failed writes are handed
+// to errs. It returns the first error encountered.
+func writeSinks(errs *FactorLog, sinks []*Sink, context LogContext, stack []byte) error {
+	var err error
+	for _, s := range sinks {
+		if context.Severity&s.severities.get() == 0 {
+			continue
+		}
+
+		if werr := s.write(errs, context, stack); werr != nil && err == nil {
+			err = werr
+		}
+	}
+
+	return err
+}
+
+// write formats the record and writes it, followed by stack if it isn't
+// nil. Formatters that are safe for concurrent use format outside of
+// the lock, so only the write is serialized. A failed write is counted
+// and handed to errs, if it isn't nil.
+func (s *Sink) write(errs *FactorLog, context LogContext, stack []byte) error {
+	o := s.load()
+	var bp *[]byte
+	if o.appender != nil {
+		bp = getBuffer()
+		defer putBuffer(bp)
+	}
+
+	var b []byte
+	formatted := o.concurrent
+	if formatted {
+		b = o.format(bp, context)
+	}
+
+	s.mu.Lock()
+	// The writer or formatter may have been replaced while formatting.
+	// Only the output loaded under the lock is written to.
+	if cur := s.load(); cur != o {
+		o = cur
+		formatted = false
+		if o.appender != nil && bp == nil {
+			bp = getBuffer()
+			defer putBuffer(bp)
+		}
+	}
+	if !formatted {
+		b = o.format(bp, context)
+	}
+
+	// failed and failedStack are the parts that couldn't be written.
+	var failed, failedStack []byte
+	_, err := o.out.Write(b)
+	if err != nil {
+		failed = b
+	}
+	if stack != nil {
+		if _, serr := o.out.Write(stack); serr != nil {
+			failedStack = stack
+			if err == nil {
+				err = serr
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		atomic.AddUint64(&s.errors, 1)
+		if errs != nil {
+			errs.writeFailed(s, context, failed, failedStack, err)
+		}
+	}
+
+	return err
+}
+
+// format formats the record, into the pooled buffer bp if the
+// formatter is an AppendFormatter.
+func (o *sinkOutput) format(bp *[]byte, context LogContext) []byte {
+	if bp != nil {
+		*bp = o.appender.AppendFormat((*bp)[:0], context)
+		return *bp
+	}
+	return o.formatter.Format(context)
+}
+
+// NewMulti creates a FactorLog that writes each record to all of
+// the given sinks that accept its severity. New(out, formatter) is
+// the same as NewMulti(NewSink(out, formatter)).
+func NewMulti(sinks ...*Sink) *FactorLog {
+	l := &FactorLog{severities: Severity(maxint32)}
+	l.sinks.Store(sinks)
+	return l
+}
+
+// AddSink adds a sink to this logger.
+func (l *FactorLog) AddSink(s *Sink) {
+	l = l.cfg()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.claimSinks()
+	old := l.loadSinks()
+	sinks := make([]*Sink, len(old), len(old)+1)
+	copy(sinks, old)
+	l.sinks.Store(append(sinks, s))
+}
+
+// RemoveSink removes a sink from this logger.
+func (l *FactorLog) RemoveSink(s *Sink) {
+	l = l.cfg()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.claimSinks()
+	old := l.loadSinks()
+	sinks := make([]*Sink, 0, len(old))
+	for _, ls := range old {
+		if ls != s {
+			sinks = append(sinks, ls)
+		}
+	}
+	l.sinks.Store(sinks)
+}
+
+// Sinks returns the sinks of this logger.
+func (l *FactorLog) Sinks() []*Sink {
+	return append([]*Sink(nil), l.cfg().loadSinks()...)
+}
+
+// AddSink adds a sink to the standard logger.
+func AddSink(s *Sink) {
+	std.AddSink(s)
+}
+
+// RemoveSink removes a sink from the standard logger.
+func RemoveSink(s *Sink) {
+	std.RemoveSink(s)
+}
@@ -0,0 +1,237 @@
+package factorlog
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fileFormatter records the file of the last record it formatted.
+type fileFormatter struct {
+	caller bool
+	file   string
+}
+
+func (f *fileFormatter) ShouldRuntimeCaller() bool {
+	return f.caller
+}
+
+func (f *fileFormatter) Format(context LogContext) []byte {
+	f.file = context.File
+	return []byte(formatMessage(context) + "\n")
+}
+
+func TestSinks(t *testing.T) {
+	buf1 := &bytes.Buffer{}
+	buf2 := &bytes.Buffer{}
+	console := NewSink(buf1, NewStdFormatter("%{SEVERITY} %{Message}"))
+	console.SetMinMaxSeverity(INFO, PANIC)
+	file := NewSink(buf2, NewJSONFormatter())
+	l := NewMulti(console, file)
+
+	l.Debug("debug")
+	l.Info("info")
+
+	if buf1.String() != "INFO info\n" {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", "INFO info\n", buf1.String())
+	}
+	if bytes.Count(buf2.Bytes(), []byte("\n")) != 2 {
+		t.Fatalf("expected two JSON records, got %#v", buf2.String())
+	}
+
+	// The logger's severities apply before the sinks'.
+	buf1.Reset()
+	buf2.Reset()
+	l.SetSeverities(DEBUG)
+	l.Info("info")
+	if buf1.Len() > 0 || buf2.Len() > 0 {
+		t.Fatal("expected no output when the logger doesn't accept the severity")
+	}
+
+	l.SetSeverities(Severity(maxint32))
+	l.RemoveSink(file)
+	l.Info("info")
+	if buf2.Len() > 0 {
+		t.Fatal("expected no output to a removed sink")
+	}
+	if len(l.Sinks()) != 1 {
+		t.Fatalf("expected 1 sink, got %d", len(l.Sinks()))
+	}
+}
+
+func TestSinksSharedCaller(t *testing.T) {
+	f1 := &fileFormatter{caller: true}
+	f2 := &fileFormatter{caller: false}
+	f3 := &fileFormatter{caller: true}
+	s3 := NewSink(&bytes.Buffer{}, f3)
+	s3.SetSeverities(ERROR)
+	l := NewMulti(NewSink(&bytes.Buffer{}, f1), NewSink(&bytes.Buffer{}, f2), s3)
+
+	l.Info("hey")
+	if f1.file == "" {
+		t.Fatal("expected the caller to be resolved for a sink that needs it")
+	}
+	if f2.file != f1.file {
+		t.Fatal("expected the caller to be shared with every sink")
+	}
+	if f3.file != "" {
+		t.Fatal("expected a sink that doesn't accept the severity to be skipped")
+	}
+
+	f1.file = ""
+	l.RemoveSink(l.Sinks()[0])
+	l.Info("hey")
+	if f2.file != "" {
+		t.Fatal("expected runtime.Caller not to be called when no sink needs it")
+	}
+}
+
+func TestSetOutputKeepsSinkSeverities(t *testing.T) {
+	buf := &bytes.Buffer{}
+	s := NewSink(&bytes.Buffer{}, NewStdFormatter("%{Message}"))
+	s.SetSeverities(ERROR)
+	l := NewMulti(s)
+	l.SetOutput(buf)
+
+	l.Info("should not appear")
+	l.Error("hey")
+	if buf.String() != "hey\n" {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", "hey\n", buf.String())
+	}
+}
+
+func TestSetFormatterConcurrent(t *testing.T) {
+	// bytes.Buffer isn't safe for concurrent use, so the race detector
+	// catches writes that aren't serialized by the sink's lock.
+	buf := &bytes.Buffer{}
+	l := New(buf, NewStdFormatter("%{Message}"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				l.Info("hey")
+			}
+		}()
+	}
+	for i := 0; i < 1000; i++ {
+		l.SetFormatter(NewStdFormatter("%{SEVERITY} %{Message}"))
+		l.SetFlags(Lshortfile)
+	}
+	wg.Wait()
+}
+
+func TestSetFormatterKeepsSink(t *testing.T) {
+	s := NewSink(&failWriter{}, NewStdFormatter("%{Message}"))
+	l := NewMulti(s)
+	l.Info("fails")
+	buf := &bytes.Buffer{}
+	l.SetOutput(buf)
+	l.SetFormatter(NewStdFormatter("%{SEVERITY} %{Message}"))
+
+	if l.Sinks()[0] != s || s.Writer() != buf || s.WriteErrors() != 1 {
+		t.Fatal("expected SetOutput and SetFormatter to change the sink in place")
+	}
+	s.SetSeverities(ERROR)
+	l.Info("should not appear")
+	l.Error("hey")
+	if buf.String() != "ERROR hey\n" {
+		t.Fatalf("expected the sink's severities to apply, got %#v", buf.String())
+	}
+	l.RemoveSink(s)
+	if len(l.Sinks()) != 0 {
+		t.Fatal("expected RemoveSink to remove the changed sink")
+	}
+}
+
+func TestSetOutputNoSinks(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewMulti()
+	l.SetOutput(buf)
+	l.Info("hey")
+	if !strings.HasSuffix(buf.String(), " hey\n") {
+		t.Fatalf("expected the new sink to use the default format, got %#v", buf.String())
+	}
+
+	l = NewMulti()
+	l.SetFormatter(NewStdFormatter("%{Message}"))
+	if l.Writer() != os.Stderr {
+		t.Fatal("expected the new sink to write to os.Stderr")
+	}
+}
+
+// blockingFormatter is a concurrent formatter whose Format waits until
+// release is closed, after closing entered.
+type blockingFormatter struct {
+	entered, release chan struct{}
+}
+
+func (f *blockingFormatter) ShouldRuntimeCaller() bool {
+	return false
+}
+
+func (f *blockingFormatter) ConcurrentSafe() bool {
+	return true
+}
+
+func (f *blockingFormatter) Format(context LogContext) []byte {
+	close(f.entered)
+	<-f.release
+	return []byte(formatMessage(context) + "\n")
+}
+
+func TestSetOutputInFlight(t *testing.T) {
+	// A record formatted while the output is replaced is written to
+	// the new writer, not the old one.
+	old, buf := &bytes.Buffer{}, &bytes.Buffer{}
+	f := &blockingFormatter{make(chan struct{}), make(chan struct{})}
+	l := New(old, f)
+	done := make(chan struct{})
+	go func() {
+		l.Info("hey")
+		close(done)
+	}()
+	<-f.entered
+	l.SetOutput(buf)
+	l.SetFormatter(NewStdFormatter("%{SEVERITY} %{Message}"))
+	close(f.release)
+	<-done
+	if old.Len() > 0 || buf.String() != "INFO hey\n" {
+		t.Fatalf("expected the record to go to the new output, got %#v and %#v", old.String(), buf.String())
+	}
+
+	// SetOutput waits for a record being written to the old writer.
+	w := &blockingWriter{make(chan struct{}), make(chan struct{})}
+	l.SetOutput(w)
+	go l.Info("hey")
+	<-w.entered
+	set := make(chan struct{})
+	go func() {
+		l.SetOutput(buf)
+		close(set)
+	}()
+	select {
+	case <-set:
+		t.Fatal("expected SetOutput to wait for the write to finish")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(w.release)
+	<-set
+}
+
+// blockingWriter is a writer whose first Write waits until release
+// is closed, after closing entered.
+type blockingWriter struct {
+	entered, release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	close(w.entered)
+	<-w.release
+	return len(p), nil
+}
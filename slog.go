@@ -0,0 +1,202 @@
+//go:build go1.21
+// +build go1.21
+
+package factorlog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"runtime"
+)
+
+// contextBackground is used by SlogFormatter.Format, where the record
+// shadows the context package.
+var contextBackground = context.Background()
+
+// SlogLevels maps each Severity, by SeverityToIndex(), to the slog
+// level of the records SlogFormatter passes to a slog.Handler.
+var SlogLevels = [...]slog.Level{
+	slog.LevelInfo,       // NONE
+	slog.LevelDebug - 4,  // TRACE
+	slog.LevelDebug,      // DEBUG
+	slog.LevelInfo,       // INFO
+	slog.LevelWarn,       // WARN
+	slog.LevelError,      // ERROR
+	slog.LevelError + 4,  // CRITICAL
+	slog.LevelError,      // STACK
+	slog.LevelError + 8,  // FATAL
+	slog.LevelError + 12, // PANIC
+}
+
+// slogSeverity maps a slog level to a severity: below LevelDebug is
+// TRACE, then DEBUG, INFO, WARN and ERROR, and LevelError+4 and above
+// is CRITICAL. FATAL and PANIC are never used, since a slog record
+// doesn't exit or panic.
+func slogSeverity(level slog.Level) Severity {
+	switch {
+	case level < slog.LevelDebug:
+		return TRACE
+	case level < slog.LevelInfo:
+		return DEBUG
+	case level < slog.LevelWarn:
+		return INFO
+	case level < slog.LevelError:
+		return WARN
+	case level < slog.LevelError+4:
+		return ERROR
+	}
+	return CRITICAL
+}
+
+// slogHandler is a slog.Handler writing through a FactorLog.
+type slogHandler struct {
+	l      *FactorLog
+	fields []Field // from WithAttrs
+	group  string  // prefix of the keys of later attributes
+}
+
+// NewSlogHandler returns a slog.Handler that writes records through l,
+// so code using log/slog shares l's severities, formatters and sinks.
+// Levels are mapped to severities (see SlogLevels for the reverse), the
+// message is the record's message and attributes become fields, like
+// those added with With(). Attributes in groups are named by joining
+// the group and attribute names with dots.
+// Example:
+//   logger := slog.New(factorlog.NewSlogHandler(log))
+//   logger.Info("hello", "user", name)
+func NewSlogHandler(l *FactorLog) slog.Handler {
+	return &slogHandler{l: l}
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	sinks, _ := h.l.cfg().sinksFor(slogSeverity(level))
+	return sinks != nil
+}
+
+func (h *slogHandler) Handle(_ context.Context, r slog.Record) error {
+	l := h.l.cfg()
+	sev := slogSeverity(r.Level)
+	sinks, needCaller := l.sinksFor(sev)
+	if sinks == nil {
+		return nil
+	}
+
+	fields := make([]Field, 0, len(h.l.fields)+len(h.fields)+r.NumAttrs())
+	fields = append(fields, h.l.fields...)
+	fields = append(fields, h.fields...)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendSlogAttr(fields, h.group, a)
+		return true
+	})
+
+	context := LogContext{
+		Time:     r.Time,
+		Severity: sev,
+		Pid:      pid,
+		Args:     []interface{}{r.Message},
+		Fields:   fields,
+		Name:     l.name,
+	}
+
+	dedup := l.loadDedup()
+	filters := l.loadFilters()
+	if (needCaller || dedup != nil || (filters != nil && filters.caller) || l.loadSampler().needsCaller()) && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		context.PC = r.PC
+		context.File = frame.File
+		context.Line = frame.Line
+		context.Function = frame.Function
+	}
+
+	if !l.admit(&context, filters, dedup) {
+		return nil
+	}
+	return l.dispatch(sinks, context, nil)
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := append([]Field(nil), h.fields...)
+	for _, a := range attrs {
+		fields = appendSlogAttr(fields, h.group, a)
+	}
+	return &slogHandler{l: h.l, fields: fields, group: h.group}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{l: h.l, fields: h.fields, group: h.group + name + "."}
+}
+
+// appendSlogAttr appends a as a field, with its key prefixed by group.
+// Groups are flattened, and empty attributes are left out.
+func appendSlogAttr(fields []Field, group string, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			group += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			fields = appendSlogAttr(fields, group, ga)
+		}
+		return fields
+	}
+
+	return append(fields, Field{group + a.Key, a.Value.Any()})
+}
+
+// SlogFormatter forwards records to a slog.Handler instead of
+// formatting them, so FactorLog records end up in a slog pipeline.
+// Fields become attributes, and the name of a named logger becomes a
+// "logger" attribute. NewSlogSink() is the easiest way to use it.
+type SlogFormatter struct {
+	Handler slog.Handler
+
+	// AddSource looks up the caller of each record, for handlers
+	// that report the source of a record.
+	AddSource bool
+}
+
+// NewSlogSink returns a sink that forwards records to h. The sink
+// has no real writer: Format passes the record to h and returns
+// nothing to write.
+// Example:
+//   log := factorlog.NewMulti(factorlog.NewSlogSink(slog.Default().Handler()))
+func NewSlogSink(h slog.Handler) *Sink {
+	return NewSink(io.Discard, &SlogFormatter{Handler: h, AddSource: true})
+}
+
+func (f *SlogFormatter) ShouldRuntimeCaller() bool {
+	return f.AddSource
+}
+
+// ConcurrentSafe returns true, since a slog.Handler must be safe for
+// concurrent use. See ConcurrentFormatter.
+func (f *SlogFormatter) ConcurrentSafe() bool {
+	return true
+}
+
+// Format passes the record to the handler and returns nil.
+func (f *SlogFormatter) Format(context LogContext) []byte {
+	level := SlogLevels[SeverityToIndex(context.Severity)]
+	ctx := contextBackground
+	if !f.Handler.Enabled(ctx, level) {
+		return nil
+	}
+
+	r := slog.NewRecord(context.Time, level, formatMessage(context), context.PC)
+	for _, field := range context.Fields {
+		r.AddAttrs(slog.Any(field.Key, field.Value))
+	}
+	if context.Name != "" {
+		r.AddAttrs(slog.String("logger", context.Name))
+	}
+	f.Handler.Handle(ctx, r)
+	return nil
+}
@@ -0,0 +1,56 @@
+//go:build go1.21
+// +build go1.21
+
+package factorlog
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(buf, NewStdFormatter("%{SEVERITY} %{Message} %{Fields} %{File}"))
+	log.SetSeverities(INFO | WARN | ERROR | CRITICAL)
+	logger := slog.New(NewSlogHandler(log.With("app", "test")))
+
+	logger.Debug("hidden")
+	if logger.Enabled(nil, slog.LevelDebug) || buf.Len() != 0 {
+		t.Fatal("expected slog debug records to be filtered by the logger's severities")
+	}
+
+	logger.WithGroup("req").With("id", 1).Warn("hello", slog.Group("user", "name", "bob"), slog.Attr{})
+	expect := "WARN hello app=test req.id=1 req.user.name=bob slog_test.go\n"
+	if buf.String() != expect {
+		t.Fatalf("expected %#v, got %#v", expect, buf.String())
+	}
+
+	buf.Reset()
+	logger.Log(nil, slog.LevelError+4, "critical")
+	if !strings.HasPrefix(buf.String(), "CRITICAL critical") {
+		t.Fatalf("expected a CRITICAL record, got %#v", buf.String())
+	}
+}
+
+func TestSlogSink(t *testing.T) {
+	buf := &bytes.Buffer{}
+	h := slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	log := NewMulti(NewSlogSink(h))
+
+	log.With("user", "bob").Warnf("hello %d", 1)
+	log.Trace("hidden")
+	expect := "level=WARN msg=\"hello 1\" user=bob\n"
+	if buf.String() != expect {
+		t.Fatalf("expected %#v, got %#v", expect, buf.String())
+	}
+}
@@ -0,0 +1,137 @@
+package factorlog
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The sockets a local syslog daemon commonly listens on.
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogWriter is an io.Writer that sends each Write as one syslog
+// message. Pair it with a SyslogFormatter:
+//   w, err := factorlog.NewSyslogWriter("", "")
+//   if err != nil {
+//     ...
+//   }
+//   log := factorlog.New(w, factorlog.NewSyslogFormatter(factorlog.SyslogDaemon))
+// Over TCP, messages are framed with octet counting (RFC 6587), and over
+// a unix stream socket they end with a newline, with newlines in the
+// message escaped as #012. Datagrams are sent
+// without a trailing newline. If the connection fails, the writer
+// dials again and retries the write once.
+type SyslogWriter struct {
+	mu      sync.Mutex
+	network string
+	addr    string
+	conn    net.Conn
+	stream  bool // conn is a unix stream socket
+	closed  bool
+	buf     []byte
+}
+
+// NewSyslogWriter connects to the syslog daemon at addr over network,
+// which is "unixgram", "unix", "udp" or "tcp". If both are empty, the
+// local daemon is found at /dev/log or one of the other usual sockets.
+func NewSyslogWriter(network, addr string) (*SyslogWriter, error) {
+	w := &SyslogWriter{network: network, addr: addr}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *SyslogWriter) connect() error {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+
+	if w.network != "" || w.addr != "" {
+		conn, err := net.DialTimeout(w.network, w.addr, 10*time.Second)
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+		w.stream = strings.HasPrefix(w.network, "unix") && w.network != "unixgram"
+		return nil
+	}
+
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range syslogSockets {
+			if conn, err := net.Dial(network, path); err == nil {
+				w.conn = conn
+				w.stream = network == "unix"
+				return nil
+			}
+		}
+	}
+	return errors.New("factorlog: no local syslog socket found")
+}
+
+// Write sends p as a single message.
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	msg := bytes.TrimSuffix(p, []byte{'\n'})
+	switch {
+	case strings.HasPrefix(w.network, "tcp"):
+		w.buf = strconv.AppendInt(w.buf[:0], int64(len(msg)), 10)
+		w.buf = append(w.buf, ' ')
+		w.buf = append(w.buf, msg...)
+		msg = w.buf
+	case w.stream:
+		// A newline ends a message, so embedded ones are escaped
+		// the way rsyslog escapes control characters.
+		w.buf = w.buf[:0]
+		for {
+			i := bytes.IndexByte(msg, '\n')
+			if i < 0 {
+				break
+			}
+			w.buf = append(w.buf, msg[:i]...)
+			w.buf = append(w.buf, "#012"...)
+			msg = msg[i+1:]
+		}
+		w.buf = append(w.buf, msg...)
+		w.buf = append(w.buf, '\n')
+		msg = w.buf
+	}
+
+	if w.conn != nil {
+		if _, err := w.conn.Write(msg); err == nil {
+			return len(p), nil
+		}
+	}
+
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+	if _, err := w.conn.Write(msg); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the connection to the syslog daemon.
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,161 @@
+package factorlog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSyslogTestLog(t *testing.T, network, addr string) (*FactorLog, *SyslogWriter) {
+	w, err := NewSyslogWriter(network, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := NewSyslogFormatter(SyslogUser)
+	f.Caller = false
+	f.Hostname = "myhost"
+	f.AppName = "myapp"
+	return New(w, f), w
+}
+
+// readPackets reads n datagrams from conn.
+func readPackets(t *testing.T, conn net.PacketConn, n int) []string {
+	var packets []string
+	buf := make([]byte, 64<<10)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for i := 0; i < n; i++ {
+		size, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		packets = append(packets, string(buf[:size]))
+	}
+	return packets
+}
+
+func checkSyslogMessages(t *testing.T, messages []string) {
+	prefix := "1 " + time.Now().Format("2006-01-02")
+	expect := []string{"<14>", " myhost myapp " + strconv.Itoa(os.Getpid()) + " - - hello", "<11>", " - - multi\nline"}
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+	for i, m := range messages {
+		if !strings.HasPrefix(m, expect[i*2]+prefix) || !strings.HasSuffix(m, expect[i*2+1]) {
+			t.Fatalf("unexpected message %q", m)
+		}
+	}
+}
+
+func TestSyslogWriterUnixgram(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	conn, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Skipf("unixgram not supported: %v", err)
+	}
+	defer conn.Close()
+
+	log, w := newSyslogTestLog(t, "unixgram", path)
+	defer w.Close()
+	log.Info("hello")
+	log.Error("multi\nline")
+	checkSyslogMessages(t, readPackets(t, conn, 2))
+}
+
+func TestSyslogWriterUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets not supported: %v", err)
+	}
+	defer ln.Close()
+
+	log, w := newSyslogTestLog(t, "unix", path)
+	defer w.Close()
+	log.Info("hello")
+	log.Error("multi\nline")
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	// Each message is a line, so the newline in the second one is
+	// escaped.
+	r := bufio.NewReader(conn)
+	var messages []string
+	for i := 0; i < 2; i++ {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, strings.Replace(strings.TrimSuffix(line, "\n"), "#012", "\n", -1))
+	}
+	checkSyslogMessages(t, messages)
+}
+
+func TestSyslogWriterUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	log, w := newSyslogTestLog(t, "udp", conn.LocalAddr().String())
+	defer w.Close()
+	log.Info("hello")
+	log.Error("multi\nline")
+	checkSyslogMessages(t, readPackets(t, conn, 2))
+
+	w.Close()
+	if _, err := w.Write([]byte("x")); err == nil {
+		t.Fatal("expected an error writing to a closed writer")
+	}
+}
+
+func TestSyslogWriterTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	log, w := newSyslogTestLog(t, "tcp", ln.Addr().String())
+	defer w.Close()
+	log.Info("hello")
+	log.Error("multi\nline")
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	// Read octet counted frames.
+	r := bufio.NewReader(conn)
+	var messages []string
+	for i := 0; i < 2; i++ {
+		length, err := r.ReadString(' ')
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+		if err != nil {
+			t.Fatalf("bad frame length %q", length)
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, string(msg))
+	}
+	checkSyslogMessages(t, messages)
+}
@@ -0,0 +1,196 @@
+package factorlog
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// vmoduleFilter is one pattern=N rule of a vmodule spec.
+type vmoduleFilter struct {
+	pattern string
+	level   Level
+	// number of path elements the pattern matches against
+	elems int
+}
+
+// match tests whether the filter matches the source file or the
+// package of a call site. file has its .go extension removed and
+// pkg is the full import path of the package.
+func (f vmoduleFilter) match(file, pkg string) bool {
+	if ok, _ := path.Match(f.pattern, lastElems(file, f.elems)); ok {
+		return true
+	}
+	ok, _ := path.Match(f.pattern, lastElems(pkg, f.elems))
+	return ok
+}
+
+// lastElems returns the last n slash separated elements of p.
+func lastElems(p string, n int) string {
+	i := len(p)
+	for ; n > 0; n-- {
+		i = strings.LastIndexByte(p[:i], '/')
+		if i < 0 {
+			return p
+		}
+	}
+	return p[i+1:]
+}
+
+// vmodule holds the filters set by SetVModule and the level they
+// resolve to for each call site seen so far.
+type vmodule struct {
+	spec    string
+	filters []vmoduleFilter
+
+	mu    sync.Mutex   // serializes additions to cache
+	cache atomic.Value // map[uintptr]Level; copied on write
+}
+
+func parseVModule(spec string) (*vmodule, error) {
+	vm := &vmodule{spec: spec}
+	for _, rule := range strings.Split(spec, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		eq := strings.LastIndexByte(rule, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("factorlog: vmodule rule %q is not of the form pattern=N", rule)
+		}
+
+		pattern := strings.TrimSuffix(strings.TrimSpace(rule[:eq]), ".go")
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("factorlog: vmodule pattern %q: %v", pattern, err)
+		}
+
+		level, err := strconv.ParseInt(strings.TrimSpace(rule[eq+1:]), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("factorlog: vmodule level in %q: %v", rule, err)
+		}
+
+		vm.filters = append(vm.filters, vmoduleFilter{
+			pattern: pattern,
+			level:   Level(level),
+			elems:   strings.Count(pattern, "/") + 1,
+		})
+	}
+
+	if len(vm.filters) == 0 {
+		return nil, nil
+	}
+
+	vm.cache.Store(map[uintptr]Level{})
+	return vm, nil
+}
+
+// level returns the verbosity for the call site at pc. The result
+// is cached, so only the first call from each site walks the filters.
+func (vm *vmodule) level(pc uintptr) Level {
+	if level, ok := vm.cache.Load().(map[uintptr]Level)[pc]; ok {
+		return level
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	file := strings.TrimSuffix(frame.File, ".go")
+	pkg := funcPackage(frame.Function)
+
+	// A call site that matches no filter is cached as -1 so it
+	// never passes the check on its own.
+	level := Level(-1)
+	for _, f := range vm.filters {
+		if f.match(file, pkg) {
+			level = f.level
+			break
+		}
+	}
+
+	vm.mu.Lock()
+	old := vm.cache.Load().(map[uintptr]Level)
+	cache := make(map[uintptr]Level, len(old)+1)
+	for k, v := range old {
+		cache[k] = v
+	}
+	cache[pc] = level
+	vm.cache.Store(cache)
+	vm.mu.Unlock()
+
+	return level
+}
+
+// funcPackage returns the import path of the package of the given
+// function name (e.g. github.com/kdar/factorlog.(*FactorLog).Info
+// returns github.com/kdar/factorlog).
+func funcPackage(function string) string {
+	slash := strings.LastIndexByte(function, '/')
+	if dot := strings.IndexByte(function[slash+1:], '.'); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
+
+// isV tests the verbosity of the call site calldepth frames above
+// the caller of isV. The global verbosity is checked first; vmodule
+// filters only come into play when it fails.
+func (l *FactorLog) isV(level Level, calldepth int) bool {
+	l = l.cfg()
+	if l.owner(ownVerbosity).verbosity.get() >= level {
+		return true
+	}
+
+	vm, _ := l.owner(ownVModule).vmodule.Load().(*vmodule)
+	if vm == nil {
+		return false
+	}
+
+	var pcs [1]uintptr
+	// skip runtime.Callers, isV and calldepth more frames
+	if runtime.Callers(calldepth+2, pcs[:]) == 0 {
+		return false
+	}
+
+	return vm.level(pcs[0]) >= level
+}
+
+// SetVModule sets per file or package verbosity, like glog's -vmodule
+// flag. spec is a comma separated list of pattern=N rules. A pattern
+// without a slash is matched against the name of the source file (without
+// .go) and the name of the package of the call to V() or IsV(). A pattern
+// with slashes is matched against as many trailing elements of the file's
+// path or the package's import path. Patterns may use the wildcards of
+// path.Match. The first matching rule sets the verbosity for the call
+// site, which is used if it is higher than the verbosity set by
+// SetVerbosity(). An empty spec removes all rules.
+// Example:
+//   log.SetVModule("server=3,db/*=2")
+func (l *FactorLog) SetVModule(spec string) error {
+	vm, err := parseVModule(spec)
+	if err != nil {
+		return err
+	}
+
+	l = l.cfg()
+	l.vmodule.Store(vm)
+	l.setOwn(ownVModule)
+	return nil
+}
+
+// VModule returns the spec last set with SetVModule.
+func (l *FactorLog) VModule() string {
+	vm, _ := l.cfg().owner(ownVModule).vmodule.Load().(*vmodule)
+	if vm == nil {
+		return ""
+	}
+	return vm.spec
+}
+
+// SetVModule sets per file or package verbosity for the standard
+// logger. See FactorLog.SetVModule().
+func SetVModule(spec string) error {
+	return std.SetVModule(spec)
+}
@@ -0,0 +1,85 @@
+package factorlog
+
+import (
+	"bytes"
+	"testing"
+)
+
+var vmoduleTests = []struct {
+	spec  string
+	level Level
+	isV   bool
+}{
+	{"", 1, false},
+	{"vmodule_test=2", 2, true},
+	{"vmodule_test=2", 3, false},
+	{"vmodule_*=3", 3, true},
+	{"other=5,vmodule_test.go=1", 1, true},
+	{"factorlog=2", 2, true},
+	{"kdar/factor*=2", 2, true},
+	{"*/vmodule_test=4", 4, true},
+	{"other=5", 1, false},
+	// the first matching rule wins
+	{"vmodule_test=1, *=5", 2, false},
+}
+
+func TestVModule(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, NewStdFormatter("%{Message}"))
+	for _, tt := range vmoduleTests {
+		if err := l.SetVModule(tt.spec); err != nil {
+			t.Fatalf("SetVModule(%q): %v", tt.spec, err)
+		}
+		if l.VModule() != tt.spec && tt.spec != "" {
+			t.Fatalf("expected VModule() to return %q, got %q", tt.spec, l.VModule())
+		}
+
+		// Check twice so the cached result is tested too.
+		for i := 0; i < 2; i++ {
+			if l.IsV(tt.level) != tt.isV {
+				t.Fatalf("SetVModule(%q): expected IsV(%d) to be %v", tt.spec, tt.level, tt.isV)
+			}
+			if l.V(tt.level).True != tt.isV {
+				t.Fatalf("SetVModule(%q): expected V(%d).True to be %v", tt.spec, tt.level, tt.isV)
+			}
+		}
+	}
+}
+
+func TestVModuleWithVerbosity(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, NewStdFormatter("%{Message}"))
+	l.SetVerbosity(3)
+	l.SetVModule("vmodule_test=1")
+
+	// The global verbosity still applies when it's higher.
+	if !l.IsV(3) {
+		t.Fatal("expected the global verbosity to apply")
+	}
+
+	l.SetVerbosity(0)
+	l.V(1).Info("should appear")
+	l.V(2).Info("should not appear")
+	l.With("k", "v").V(1).V(1).Info("should appear")
+	if buf.String() != "should appear\nshould appear\n" {
+		t.Fatalf("\nexpected: %#v\ngot:      %#v", "should appear\nshould appear\n", buf.String())
+	}
+}
+
+func TestVModuleErrors(t *testing.T) {
+	l := New(&bytes.Buffer{}, NewStdFormatter("%{Message}"))
+	for _, spec := range []string{"server", "=3", "server=x", "[=3"} {
+		if err := l.SetVModule(spec); err == nil {
+			t.Fatalf("expected an error for %q", spec)
+		}
+	}
+}
+
+func BenchmarkVModuleIsV(b *testing.B) {
+	l := New(&bytes.Buffer{}, NewStdFormatter("%{Message}"))
+	l.SetVModule("server=3,db/*=2")
+	b.ResetTimer()
+	for x := 0; x < b.N; x++ {
+		l.IsV(1)
+	}
+}